@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"goddns/internal/config"
+	"goddns/internal/platform/ifaddr"
+	"goddns/internal/updater"
+)
+
+var (
+	addrsConfigPath string
+	addrsInterface  string
+	addrsAll        bool
+	addrsJSON       bool
+)
+
+// addrReport is one address as printed by `goddns addrs --json`
+type addrReport struct {
+	updater.AddressStatus
+	Rank        int    `json:"rank,omitempty"` // 1 为将被发布的地址，0 表示被排除
+	SuffixMatch bool   `json:"suffix_match,omitempty"`
+	StateMatch  bool   `json:"state_match,omitempty"`
+	Kept        bool   `json:"kept,omitempty"`
+	Rejected    string `json:"rejected,omitempty"`
+}
+
+type ifaceReport struct {
+	Name      string       `json:"name"`
+	Addresses []addrReport `json:"addresses"`
+}
+
+type addrsReport struct {
+	Order      []string      `json:"order"`
+	Current    string        `json:"current,omitempty"` // policy.sticky 保留的地址
+	Interfaces []ifaceReport `json:"interfaces"`
+}
+
+var addrsCmd = &cobra.Command{
+	Use:   "addrs",
+	Short: "Show the interface addresses and why each one is or is not selected",
+	Long: `Show every IPv6 address of get_ip.interface (or -i, or all interfaces with -a)
+with its scope, state, lifetimes and flags, how the selection policy ranks it
+and why it was rejected. Without the default config file the default policy is used.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, configFile, err := config.LoadGetIP(addrsConfigPath)
+		if err != nil {
+			// 只有默认配置文件不存在时才退回默认策略
+			if cmd.Flags().Changed("file") || !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			cfg, configFile = config.Config{}, ""
+		}
+
+		name := addrsInterface
+		if name == "" && !addrsAll {
+			name = cfg.GetIP.Interface
+		}
+		var names []string
+		if name != "" {
+			names = []string{name}
+		} else {
+			ifaces, err := net.Interfaces()
+			if err != nil {
+				return err
+			}
+			for _, iface := range ifaces {
+				names = append(names, iface.Name)
+			}
+		}
+
+		report := addrsReport{Order: ifaddr.SelectionOrder(cfg)}
+		if configFile != "" && cfg.GetIP.Policy != nil && cfg.GetIP.Policy.Sticky {
+			report.Current = config.ReadLastIP(config.GetCacheFilePath(configFile, cfg.WorkDir))
+		}
+		for _, n := range names {
+			infos, err := ifaddr.GetAvailableIPv6(n)
+			if err != nil {
+				// 列出全部网卡时跳过出错的网卡，在 stderr 中说明原因
+				if len(names) > 1 {
+					fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", n, err)
+					continue
+				}
+				return err
+			}
+			ir := ifaceReport{Name: n}
+			for _, ev := range ifaddr.EvaluateIPv6(cfg, infos, report.Current) {
+				ir.Addresses = append(ir.Addresses, addrReport{
+					AddressStatus: updater.NewAddressStatus(ev.Info),
+					Rank:          ev.Rank,
+					SuffixMatch:   ev.SuffixMatch,
+					StateMatch:    ev.StateMatch,
+					Kept:          ev.Kept,
+					Rejected:      ev.Rejected,
+				})
+			}
+			report.Interfaces = append(report.Interfaces, ir)
+		}
+
+		if addrsJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "    ")
+			return enc.Encode(report)
+		}
+		return printAddrs(os.Stdout, report)
+	},
+}
+
+func init() {
+	addrsCmd.Flags().StringVarP(&addrsConfigPath, "file", "f", "config.json", "path to config file with get_ip settings")
+	addrsCmd.Flags().StringVarP(&addrsInterface, "interface", "i", "", "interface to inspect (default: get_ip.interface)")
+	addrsCmd.Flags().BoolVarP(&addrsAll, "all", "a", false, "inspect all interfaces")
+	addrsCmd.Flags().BoolVar(&addrsJSON, "json", false, "print JSON instead of a table")
+	rootCmd.AddCommand(addrsCmd)
+}
+
+func printAddrs(out io.Writer, report addrsReport) error {
+	fmt.Fprintf(out, "Selection order: %s\n", strings.Join(report.Order, " > "))
+	if report.Current != "" {
+		fmt.Fprintf(out, "Current address (sticky): %s\n", report.Current)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INTERFACE\tRANK\tADDRESS\tSCOPE\tSTATE\tPREFERRED\tVALID\tFLAGS\tCANDIDATE\tSUFFIX\tPREFER_STATE\tNOTE")
+	for _, ir := range report.Interfaces {
+		for _, a := range ir.Addresses {
+			rank, suffix, state := "-", "-", "-"
+			if a.Rank > 0 {
+				rank = fmt.Sprint(a.Rank)
+				suffix, state = yesNo(a.SuffixMatch), yesNo(a.StateMatch)
+			}
+			flags := "-"
+			if len(a.Flags) > 0 {
+				flags = strings.Join(a.Flags, ",")
+			}
+			note := a.Rejected
+			switch {
+			case a.Kept:
+				note = "selected (sticky, current address)"
+			case a.Rank == 1:
+				note = "selected"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", ir.Name, rank, a.IP, a.Scope, a.AddressState,
+				lifetime(a.PreferredLft), lifetime(a.ValidLft), flags, yesNo(a.IsCandidate), suffix, state, note)
+		}
+	}
+	return w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// lifetime prints seconds, showing the kernel's infinite lifetime (0xffffffff) as forever
+func lifetime(seconds int64) string {
+	if seconds >= 0xffffffff {
+		return "forever"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"goddns/internal/config"
+	"goddns/internal/control"
+)
+
+var (
+	ctlSocket string
+	ctlConfig string
+)
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running goddns daemon over its Unix socket",
+}
+
+func init() {
+	ctlCmd.PersistentFlags().StringVarP(&ctlSocket, "socket", "s", "", "control socket path (default: control_socket from -f, or "+config.DefaultControlSocket+")")
+	ctlCmd.PersistentFlags().StringVarP(&ctlConfig, "file", "f", "", "config file to read control_socket from")
+
+	ctlCmd.AddCommand(
+		ctlCommand("update", "Force an update of all records, ignoring the cache", control.CmdUpdate, false),
+		ctlCommand("reload", "Reload the config file", control.CmdReload, false),
+		ctlCommand("pause <record>", "Stop updating a record", control.CmdPause, true),
+		ctlCommand("resume <record>", "Resume updating a paused record", control.CmdResume, true),
+		ctlCommand("status", "Print the daemon status as JSON", control.CmdStatus, false),
+	)
+	rootCmd.AddCommand(ctlCmd)
+}
+
+func ctlCommand(use, short, command string, needsRecord bool) *cobra.Command {
+	args := cobra.NoArgs
+	if needsRecord {
+		args = cobra.ExactArgs(1)
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  args,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := control.Request{Command: command}
+			if needsRecord {
+				req.Record = args[0]
+			}
+			resp, err := control.Send(resolveControlSocket(), req)
+			if err != nil {
+				return err
+			}
+			if !resp.OK {
+				return errors.New(resp.Error)
+			}
+			if command == control.CmdStatus || command == control.CmdUpdate {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "    ")
+				return enc.Encode(resp.Status)
+			}
+			fmt.Println("ok")
+			return nil
+		},
+	}
+}
+
+func resolveControlSocket() string {
+	if ctlSocket != "" {
+		return ctlSocket
+	}
+	if ctlConfig != "" {
+		if cfg, file := config.ReadConfig(ctlConfig, true); file != "" && cfg.Control != "" {
+			return cfg.Control
+		}
+	}
+	return config.DefaultControlSocket
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// 编译时通过 -ldflags "-X main.version=..." 注入
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+var rootCmd = &cobra.Command{
+	Use:           "goddns",
+	Short:         "Dynamic DNS client with IPv6 support",
+	Version:       version,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.SetVersionTemplate(fmt.Sprintf("goddns %s\ncommit: %s\nbuilt: %s\n", version, commit, buildDate))
+}
+
+// Execute runs the root command
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
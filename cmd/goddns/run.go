@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"goddns/internal/config"
+	"goddns/internal/control"
+	"goddns/internal/health"
+	"goddns/internal/log"
+	"goddns/internal/systemd"
+	"goddns/internal/updater"
+)
+
+var (
+	configPath  string
+	ignoreCache bool
+	daemonMode  bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Detect the current IPv6 address and update the DNS record",
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetupDefaultLogger()
+
+		cfg, configFile := config.ReadConfig(configPath, false)
+		if configFile == "" {
+			log.Fatal("Invalid or incomplete config file: %s", configPath)
+		}
+		if err := log.Init(cfg.LogOutput); err != nil {
+			log.Fatal("%v", err)
+		}
+
+		u, err := updater.New(cfg, configFile)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		if !daemonMode {
+			err := u.Sync(ignoreCache)
+			u.Wait()
+			if err != nil {
+				log.Fatal("%v", err)
+			}
+			return
+		}
+		runDaemon(u)
+	},
+}
+
+func init() {
+	runCmd.Flags().StringVarP(&configPath, "file", "f", "config.json", "path to config file")
+	runCmd.Flags().BoolVarP(&ignoreCache, "ignore-cache", "i", false, "ignore cached IP and force update")
+	runCmd.Flags().BoolVarP(&daemonMode, "daemon", "d", false, "keep running and check every 'interval' seconds")
+	rootCmd.AddCommand(runCmd)
+}
+
+func runDaemon(u *updater.Updater) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	activated, err := systemd.Listeners()
+	if err != nil {
+		log.Fatal("Failed to use sockets passed by systemd: %v", err)
+	}
+
+	cfg := u.Config()
+	var hs *health.Server
+	if l := activatedListener(activated, "health", "tcp"); l != nil || cfg.Health != nil {
+		var hcfg config.HealthConfig
+		if cfg.Health != nil {
+			hcfg = *cfg.Health
+		}
+		hs = health.NewServer(hcfg, u)
+		if l != nil {
+			go serveHealth(hs, l)
+			log.Info("Health server listening on %s (socket activation)", l.Addr())
+		} else if hcfg.Listen != "" {
+			go func() {
+				if err := hs.ListenAndServe(); err != nil {
+					log.Error("Health server on %s failed: %v", hcfg.Listen, err)
+				}
+			}()
+			log.Info("Health server listening on %s", hcfg.Listen)
+		}
+	}
+
+	var cs *control.Server
+	if l := activatedListener(activated, "control", "unix"); l != nil || cfg.Control != "" {
+		cs = control.NewServer(u)
+		if l != nil {
+			cs.SetListener(l)
+			log.Info("Control socket listening on %s (socket activation)", l.Addr())
+		} else {
+			if err := cs.Listen(cfg.Control); err != nil {
+				log.Fatal("Failed to open control socket %s: %v", cfg.Control, err)
+			}
+			log.Info("Control socket listening on %s", cfg.Control)
+		}
+		go func() {
+			if err := cs.Serve(); err != nil {
+				log.Error("Control socket failed: %v", err)
+			}
+		}()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGUSR1)
+	defer signal.Stop(sigs)
+	go handleSignals(ctx, u, sigs)
+
+	u.OnSync(notifySystemdStatus)
+	if wd := systemd.WatchdogInterval(); wd > 0 {
+		u.SetHeartbeat(wd, func() { systemd.Notify("WATCHDOG=1") })
+		log.Info("systemd watchdog enabled, pinging every %s", wd)
+	}
+	systemd.Notify("READY=1")
+
+	log.Info("Running in daemon mode, checking every %s", cfg.CheckInterval())
+	u.Run(ctx)
+
+	systemd.Notify("STOPPING=1")
+	u.Wait()
+	if hs != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		hs.Shutdown(shutdownCtx)
+	}
+	if cs != nil {
+		cs.Close()
+	}
+	log.Info("Shutting down")
+}
+
+// activatedListener picks the socket named name, or the only unnamed socket of
+// the given network
+func activatedListener(listeners map[string]net.Listener, name, network string) net.Listener {
+	if l, ok := listeners[name]; ok {
+		return l
+	}
+	return listeners[network]
+}
+
+func serveHealth(hs *health.Server, l net.Listener) {
+	if err := hs.Serve(l); err != nil {
+		log.Error("Health server on %s failed: %v", l.Addr(), err)
+	}
+}
+
+// notifySystemdStatus reports the published address and last sync in
+// `systemctl status`
+func notifySystemdStatus(st updater.Status, err error) {
+	var ips []string
+	for _, rec := range st.Records {
+		if rec.Published != "" {
+			ips = append(ips, rec.Published)
+		}
+	}
+	ip := "none"
+	if len(ips) > 0 {
+		ip = strings.Join(ips, ", ")
+	}
+	result := "ok"
+	if err != nil {
+		result = "failed"
+	}
+	systemd.Notify(fmt.Sprintf("STATUS=IP %s, last sync %s %s", ip, time.Now().Format(time.DateTime), result))
+}
+
+// handleSignals reloads the config on SIGHUP and forces an update on SIGUSR1
+func handleSignals(ctx context.Context, u *updater.Updater, sigs <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			switch sig {
+			case syscall.SIGHUP:
+				log.Info("Received SIGHUP, reloading config")
+				systemd.Notify("RELOADING=1")
+				if err := u.Reload(); err != nil {
+					log.Error("%v", err)
+				}
+				systemd.Notify("READY=1")
+			case syscall.SIGUSR1:
+				log.Info("Received SIGUSR1, forcing update of all records")
+				if err := u.Sync(true); err != nil {
+					log.Error("Forced update failed: %v", err)
+				} else {
+					log.Success("Forced update completed")
+				}
+			}
+		}
+	}
+}
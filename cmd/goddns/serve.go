@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"goddns/internal/config"
+	"goddns/internal/dyndns"
+	"goddns/internal/log"
+	"goddns/internal/systemd"
+)
+
+var serveConfigPath string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Accept dyndns2 updates from routers and relay them to the DNS provider",
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetupDefaultLogger()
+
+		cfg, configFile := config.ReadConfig(serveConfigPath, false)
+		if configFile == "" {
+			log.Fatal("Invalid or incomplete config file: %s", serveConfigPath)
+		}
+		if err := log.Init(cfg.LogOutput); err != nil {
+			log.Fatal("%v", err)
+		}
+		if cfg.Serve == nil || len(cfg.Serve.Clients) == 0 {
+			log.Fatal("Config has no 'serve.clients', nobody could push updates")
+		}
+
+		s, err := dyndns.NewServer(cfg, configFile)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+
+		activated, err := systemd.Listeners()
+		if err != nil {
+			log.Fatal("Failed to use sockets passed by systemd: %v", err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errc := make(chan error, 1)
+		if l := activatedListener(activated, "dyndns", "tcp"); l != nil {
+			log.Info("dyndns2 server listening on %s (socket activation)", l.Addr())
+			go func() { errc <- s.Serve(l) }()
+		} else {
+			log.Info("dyndns2 server listening on %s", s.Addr())
+			go func() { errc <- s.ListenAndServe() }()
+		}
+		systemd.Notify("READY=1")
+
+		select {
+		case err := <-errc:
+			if err != nil {
+				log.Fatal("dyndns2 server failed: %v", err)
+			}
+		case <-ctx.Done():
+		}
+
+		systemd.Notify("STOPPING=1")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Shutdown(shutdownCtx)
+		log.Info("Shutting down")
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveConfigPath, "file", "f", "config.json", "path to config file")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -3,13 +3,13 @@ module goddns
 go 1.24.4
 
 require (
+	github.com/spf13/cobra v1.8.0
 	github.com/vishvananda/netlink v1.3.1
 	golang.org/x/net v0.48.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.8.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/vishvananda/netns v0.0.5 // indirect
 	golang.org/x/sys v0.39.0 // indirect
@@ -1,32 +1,225 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"goddns/internal/log"
+	"goddns/internal/systemd"
 )
 
-// CloudflareConfig Cloudflare specific settings
-type CloudflareConfig struct {
+// ProviderOptions settings of the DNS provider. api_token, zone_id and
+// proxied are used by cloudflare.
+type ProviderOptions struct {
 	APIToken string `json:"api_token"`
 	ZoneID   string `json:"zone_id,omitempty"`
 	Proxied  bool   `json:"proxied"`
 	TTL      int    `json:"ttl"`
+	Endpoint string `json:"endpoint,omitempty"` // 可选，覆盖 digitalocean、hetzner、aliyun、dnspod、desec、duckdns 等服务商的 API 地址
 	Domain   struct {
 		Zone   string `json:"zone"`
 		Record string `json:"record"`
 	} `json:"domain"`
+	RFC2136  *RFC2136Config  `json:"rfc2136,omitempty"`
+	PowerDNS *PowerDNSConfig `json:"powerdns,omitempty"`
+	Route53  *Route53Config  `json:"route53,omitempty"`
+	DynDNS2  *DynDNS2Config  `json:"dyndns2,omitempty"`
+	Aliyun   *AliyunConfig   `json:"aliyun,omitempty"`
+	DNSPod   *DNSPodConfig   `json:"dnspod,omitempty"`
+	Exec     *ExecConfig     `json:"exec,omitempty"`
+}
+
+// RFC2136Config settings of the rfc2136 provider
+type RFC2136Config struct {
+	Server       string `json:"server"`                  // 主服务器 host 或 host:port
+	Transport    string `json:"transport,omitempty"`     // udp(默认，响应截断时自动改用 tcp)或 tcp
+	KeyName      string `json:"key_name,omitempty"`      // TSIG 密钥名，为空时不签名
+	KeyAlgorithm string `json:"key_algorithm,omitempty"` // hmac-sha256(默认)或 hmac-sha512
+	KeySecret    string `json:"key_secret,omitempty"`    // base64 编码的 TSIG 密钥
+	Prerequisite string `json:"prerequisite,omitempty"`  // 可选：name_in_use、name_not_in_use、rrset_exists、rrset_not_exists
 }
 
 // IPSource source for obtaining IP
 type IPSource struct {
-	Interface string   `json:"interface,omitempty"`
-	URL       string   `json:"url,omitempty"`       // 保持原有字段兼容性
-	URLs      []string `json:"urls,omitempty"`      // 新增数组字段支持多个URL
+	Interface      string           `json:"interface,omitempty"`
+	URL            string           `json:"url,omitempty"`             // 保持原有字段兼容性
+	URLs           []string         `json:"urls,omitempty"`            // 新增数组字段支持多个URL
+	AllowTemporary bool             `json:"allow_temporary,omitempty"` // 允许选择 RFC 4941 临时地址，默认排除
+	Policy         *SelectionPolicy `json:"policy,omitempty"`          // 地址选择策略
+}
+
+// SelectionPolicy decides which of the interface addresses is published
+type SelectionPolicy struct {
+	Allow        []string `json:"allow,omitempty"`         // 只选择这些 CIDR 内的地址
+	Deny         []string `json:"deny,omitempty"`          // 排除这些 CIDR 内的地址
+	Suffix       string   `json:"suffix,omitempty"`        // 优先的接口标识，如 "::1"，或 "eui64"
+	PreferState  string   `json:"prefer_state,omitempty"`  // 优先 static 或 dynamic 地址
+	MinLifetime  int      `json:"min_lifetime,omitempty"`  // 剩余 preferred lifetime 低于该秒数的地址不选
+	Order        []string `json:"order,omitempty"`         // 比较顺序，默认 suffix、state、lifetime
+	Sticky       bool     `json:"sticky,omitempty"`        // 当前地址仍可用时不切换到其他地址
+	StickyMargin int      `json:"sticky_margin,omitempty"` // 当前地址剩余 preferred lifetime 低于该秒数时才切换
+}
+
+// SelectionCriteria are the names allowed in 'get_ip.policy.order'
+var SelectionCriteria = []string{"suffix", "state", "lifetime"}
+
+// HealthConfig settings for the optional health/status HTTP server
+type HealthConfig struct {
+	Listen         string `json:"listen,omitempty"`          // 监听地址，如 127.0.0.1:8053
+	ReadyIntervals int    `json:"ready_intervals,omitempty"` // /readyz 要求最近一次成功同步在 N 个检测周期内
+}
+
+// WebhookConfig one outgoing webhook
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Format  string            `json:"format,omitempty"`  // json(默认)、slack、discord、ntfy、gotify
+	Events  []string          `json:"events,omitempty"`  // change、failure、recovery，默认全部
+	Body    string            `json:"body,omitempty"`    // json 格式下的 text/template 请求体模板
+	Headers map[string]string `json:"headers,omitempty"`
+	Token   string            `json:"token,omitempty"`   // ntfy access token 或 gotify app token
+	Retries *int              `json:"retries,omitempty"` // 默认 3，0 表示不重试
+}
+
+// SMTPConfig email notification settings
+type SMTPConfig struct {
+	Host        string   `json:"host"`
+	Port        int      `json:"port,omitempty"`         // 默认 starttls:587、tls:465、none:25
+	Security    string   `json:"security,omitempty"`     // starttls(默认)、tls、none
+	Username    string   `json:"username,omitempty"`
+	Password    string   `json:"password,omitempty"`
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	Events      []string `json:"events,omitempty"`       // change、failure、recovery，默认全部
+	MinInterval int      `json:"min_interval,omitempty"` // 两封邮件的最小间隔(秒)，默认 600
+}
+
+// NotifyConfig notification settings
+type NotifyConfig struct {
+	FailureThreshold int             `json:"failure_threshold,omitempty"` // 连续失败 N 次后告警，默认 3
+	Webhooks         []WebhookConfig `json:"webhooks,omitempty"`
+	SMTP             *SMTPConfig     `json:"smtp,omitempty"`
+}
+
+// HooksConfig commands run around each record update via /bin/sh -c
+type HooksConfig struct {
+	PreUpdate  []string `json:"pre_update,omitempty"`  // 更新前执行，失败则放弃本次更新
+	PostUpdate []string `json:"post_update,omitempty"` // 更新成功后执行
+	OnFailure  []string `json:"on_failure,omitempty"`  // 更新失败后执行
+	Timeout    int      `json:"timeout,omitempty"`     // 单个命令超时(秒)，默认 30
+}
+
+// VerifyConfig post-update DNS verification settings
+type VerifyConfig struct {
+	Timeout     int      `json:"timeout,omitempty"`     // 等待生效的最长时间(秒)，默认 60
+	Interval    int      `json:"interval,omitempty"`    // 查询间隔(秒)，默认 5
+	Nameservers []string `json:"nameservers,omitempty"` // 覆盖自动发现的权威服务器，host 或 host:port
+	Resolvers   []string `json:"resolvers,omitempty"`   // 额外检查的公共递归解析器，如 1.1.1.1
+}
+
+// DriftConfig periodic comparison of the live record with the detected address
+type DriftConfig struct {
+	Interval    int      `json:"interval,omitempty"`    // 检查间隔(秒)，默认 3600
+	Method      string   `json:"method,omitempty"`      // api(默认，通过服务商 API 读取)或 dns(查询权威服务器)
+	Nameservers []string `json:"nameservers,omitempty"` // dns 方式下指定权威服务器，默认通过 NS 记录自动发现
+}
+
+// PowerDNSConfig settings of the powerdns provider. api_token is sent as X-API-Key.
+type PowerDNSConfig struct {
+	URL      string `json:"url"`                 // API 地址，如 http://127.0.0.1:8081
+	ServerID string `json:"server_id,omitempty"` // 默认 localhost
+}
+
+// Route53Config settings of the route53 provider. zone_id may hold the hosted
+// zone ID, otherwise it is looked up by domain.zone.
+type Route53Config struct {
+	AccessKeyID     string `json:"access_key_id,omitempty"`     // 为空时依次读取环境变量与共享凭据文件
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+	Profile         string `json:"profile,omitempty"`          // 共享凭据文件中的 profile，默认 $AWS_PROFILE 或 default
+	CredentialsFile string `json:"credentials_file,omitempty"` // 默认 $AWS_SHARED_CREDENTIALS_FILE 或 ~/.aws/credentials
+	Region          string `json:"region,omitempty"`           // 签名使用的区域，默认 us-east-1
+	Endpoint        string `json:"endpoint,omitempty"`         // 默认 https://route53.amazonaws.com
+	WaitTimeout     int    `json:"wait_timeout,omitempty"`     // 等待变更状态变为 INSYNC 的最长时间(秒)，默认 180
+}
+
+// DynDNS2Config settings of the dyndns2 provider
+type DynDNS2Config struct {
+	Server    string   `json:"server"`              // 更新地址，如 https://dynupdate.no-ip.com/nic/update
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	Hostnames []string `json:"hostnames,omitempty"` // 默认 domain.record + "." + domain.zone
+	IPParam   string   `json:"ip_param,omitempty"`  // 传递地址的参数名，默认 myip，部分服务使用 myipv6
+}
+
+// ServeClient one client allowed to push updates to `goddns serve`
+type ServeClient struct {
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	Hostnames []string `json:"hostnames"` // 允许更新的主机名，支持 *.example.com 通配
+}
+
+// ServeConfig settings of the built-in dyndns2 update server
+type ServeConfig struct {
+	Listen  string        `json:"listen,omitempty"`   // 监听地址，如 :8245
+	TLSCert string        `json:"tls_cert,omitempty"` // 可选，同时设置证书与私钥时使用 HTTPS
+	TLSKey  string        `json:"tls_key,omitempty"`
+	Clients []ServeClient `json:"clients"`
+}
+
+// AliyunConfig settings of the aliyun provider (Alibaba Cloud DNS)
+type AliyunConfig struct {
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+}
+
+// DNSPodConfig settings of the dnspod provider (Tencent Cloud API 3.0)
+type DNSPodConfig struct {
+	SecretID   string `json:"secret_id"`
+	SecretKey  string `json:"secret_key"`
+	RecordLine string `json:"record_line,omitempty"` // 解析线路，默认"默认"
+}
+
+// ExecConfig settings of the exec provider, an external program speaking the
+// JSON protocol described in the README on stdin/stdout
+type ExecConfig struct {
+	Command string          `json:"command"`           // 插件程序路径，直接执行，不经过 shell
+	Args    []string        `json:"args,omitempty"`
+	Timeout int             `json:"timeout,omitempty"` // 单次调用超时(秒)，默认 30
+	Options json.RawMessage `json:"options,omitempty"` // 原样传给插件的自定义设置
+}
+
+// HostConfig a host behind this machine whose address is composed from the
+// detected prefix and a static interface identifier, or looked up by MAC
+// address in the neighbor table
+type HostConfig struct {
+	Record       string `json:"record"`                  // 子域名，位于 domain.zone 下
+	Source       string `json:"source,omitempty"`        // prefix(默认，前缀+接口标识) 或 ndp(邻居表)
+	Suffix       string `json:"suffix,omitempty"`        // 静态接口标识，如 ::10
+	MAC          string `json:"mac,omitempty"`           // 由 MAC 地址生成 EUI-64 接口标识
+	PrefixLength int    `json:"prefix_length,omitempty"` // 保留检测到的地址的前多少位，默认 64
+}
+
+// PrimaryTarget names the provider configured at the top level when mirrors are used
+const PrimaryTarget = "primary"
+
+// MirrorTarget an additional provider the record is kept current in, e.g.
+// while migrating between providers. Empty domain and ttl are taken from the
+// primary provider_options.
+type MirrorTarget struct {
+	Name     string          `json:"name"` // 用于日志、状态与缓存文件名
+	Provider string          `json:"provider"`
+	Options  ProviderOptions `json:"provider_options"`
 }
 
 // Config main configuration structure
@@ -36,48 +229,239 @@ type Config struct {
 	WorkDir    string           `json:"work_dir"`
 	Proxy      string           `json:"proxy,omitempty"`
 	LogOutput  string           `json:"log_output,omitempty"`   // 日志输出配置: shell或文件路径
-	Cloudflare CloudflareConfig `json:"provider_options"`
+	Interval   int              `json:"interval,omitempty"`     // 守护模式下的检测间隔(秒)
+	Health     *HealthConfig    `json:"health,omitempty"`
+	Control    string           `json:"control_socket,omitempty"` // 控制接口 Unix socket 路径
+	Notify     *NotifyConfig    `json:"notify,omitempty"`
+	Hooks      *HooksConfig     `json:"hooks,omitempty"`
+	Verify     *VerifyConfig    `json:"verify,omitempty"`
+	Drift      *DriftConfig     `json:"drift_check,omitempty"`
+	Serve      *ServeConfig     `json:"serve,omitempty"`
+	Mirrors    []MirrorTarget   `json:"mirrors,omitempty"`
+	Hosts      []HostConfig     `json:"hosts,omitempty"`
+	Options    ProviderOptions  `json:"provider_options"`
+}
+
+// ApexRecord is the record name of the zone apex
+const ApexRecord = "@"
+
+// RecordFQDN returns the name of record in zone, ApexRecord being the zone itself
+func RecordFQDN(record, zone string) string {
+	if record == ApexRecord {
+		return zone
+	}
+	return record + "." + zone
+}
+
+// RecordName returns the name of the configured record
+func (c Config) RecordName() string {
+	return RecordFQDN(c.Options.Domain.Record, c.Options.Domain.Zone)
+}
+
+// providerDefaultTTL overrides the default TTL for providers that reject 180
+// (the free tiers of aliyun and dnspod, and desec's minimum of 3600)
+var providerDefaultTTL = map[string]int{
+	"aliyun": 600,
+	"dnspod": 600,
+	"desec":  3600,
+}
+
+// DefaultTTL returns the TTL used when 'provider_options.ttl' is not set
+func DefaultTTL(provider string) int {
+	if ttl, ok := providerDefaultTTL[provider]; ok {
+		return ttl
+	}
+	return 180
+}
+
+// MirrorConfig returns cfg with the provider replaced by mirror m. A mirror
+// without 'ttl' inherits the main TTL, raised to its provider's default.
+func (c Config) MirrorConfig(m MirrorTarget) Config {
+	mc := c
+	mc.Provider = m.Provider
+	mc.Options = m.Options
+	if mc.Options.Domain.Zone == "" {
+		mc.Options.Domain = c.Options.Domain
+	} else if mc.Options.Domain.Record == "" {
+		mc.Options.Domain.Record = c.Options.Domain.Record
+	}
+	if mc.Options.TTL == 0 {
+		mc.Options.TTL = max(c.Options.TTL, providerDefaultTTL[m.Provider])
+	}
+	return mc
 }
 
-// ReadConfig reads and validates config, writes back standardized JSON if needed
+// DefaultInterval is used in daemon mode when 'interval' is not set
+const DefaultInterval = 300 * time.Second
+
+// DefaultControlSocket is used by `goddns ctl` when no socket is given
+const DefaultControlSocket = "/run/goddns/goddns.sock"
+
+// CredentialAPIToken is the systemd credential name read into provider_options.api_token
+const CredentialAPIToken = "api_token"
+
+// CredentialTSIGSecret is the systemd credential name read into provider_options.rfc2136.key_secret
+const CredentialTSIGSecret = "tsig_secret"
+
+// DefaultReadyIntervals is used when 'health.ready_intervals' is not set
+const DefaultReadyIntervals = 3
+
+// CheckInterval returns the configured daemon check interval
+func (c Config) CheckInterval() time.Duration {
+	if c.Interval > 0 {
+		return time.Duration(c.Interval) * time.Second
+	}
+	return DefaultInterval
+}
+
+// ReadConfig reads and validates config, writes back standardized JSON if needed.
+// It returns an empty path when the config is unusable.
 func ReadConfig(path string, quiet bool) (Config, string) {
+	config, configFile, err := LoadConfig(path)
+	if err != nil {
+		if !quiet {
+			log.Error("%v", err)
+		}
+		return config, ""
+	}
+	return config, configFile
+}
+
+// LoadGetIP parses the config for its get_ip settings only, for diagnostics
+// that do not talk to a provider. Credentials and the other sections are not
+// checked and the file is never rewritten.
+func LoadGetIP(path string) (Config, string, error) {
+	config, configFile, err := parseConfig(path)
+	if err != nil {
+		return config, "", err
+	}
+	if config.GetIP.Policy != nil {
+		if err := validatePolicy(config.GetIP.Policy); err != nil {
+			return config, "", err
+		}
+	}
+	return config, configFile, nil
+}
+
+// parseConfig reads and decodes the config file without validating it
+func parseConfig(path string) (Config, string, error) {
 	config := Config{}
 	configFile, err := filepath.Abs(path)
 	if err != nil {
-		return config, ""
+		return config, "", err
 	}
 
 	data, err := os.ReadFile(configFile)
 	if err != nil {
-		return config, ""
+		return config, "", fmt.Errorf("failed to read config: %w", err)
 	}
 
 	if err := json.Unmarshal(data, &config); err != nil {
-		return config, ""
+		return config, "", fmt.Errorf("failed to parse config %s: %w", configFile, err)
+	}
+	return config, configFile, nil
+}
+
+// LoadConfig is ReadConfig returning the reason the config was rejected,
+// used where a bad config must not terminate the process (e.g. reload)
+func LoadConfig(path string) (Config, string, error) {
+	config, configFile, err := parseConfig(path)
+	if err != nil {
+		return config, "", err
 	}
 
 	// 直接明文处理，无需解密
 
-	if config.Provider == "" {
-		return config, ""
+	// systemd LoadCredential= 提供的凭据优先，且不会写回配置文件
+	fileToken := config.Options.APIToken
+	if token, ok := systemd.Credential(CredentialAPIToken); ok {
+		config.Options.APIToken = token
 	}
-	if config.Provider != "cloudflare" {
-		return config, ""
+	var fileSecret string
+	if config.Options.RFC2136 != nil {
+		fileSecret = config.Options.RFC2136.KeySecret
+		if secret, ok := systemd.Credential(CredentialTSIGSecret); ok {
+			rfc := *config.Options.RFC2136
+			rfc.KeySecret = secret
+			config.Options.RFC2136 = &rfc
+		}
+	}
+
+	if config.Provider == "" {
+		return config, "", errors.New("config 'provider' is required")
 	}
 
 	// 检查IP源配置，同时支持interface和urls/url字段
 	hasInterface := config.GetIP.Interface != ""
 	hasURL := config.GetIP.URL != "" || len(config.GetIP.URLs) > 0
 
-	if !hasInterface && !hasURL {
-		return config, ""
+	// 仅作为 dyndns2 网关(goddns serve)使用时不需要检测本机地址
+	if !hasInterface && !hasURL && config.Serve == nil {
+		return config, "", errors.New("config 'get_ip' needs 'interface' or 'urls'")
 	}
 
-	if config.Cloudflare.APIToken == "" {
-		return config, ""
+	if err := validateProvider(config.Provider, config.Options); err != nil {
+		return config, "", err
 	}
-	if config.Cloudflare.Domain.Zone == "" || config.Cloudflare.Domain.Record == "" {
-		return config, ""
+	if config.Options.Domain.Zone == "" || (config.Options.Domain.Record == "" && config.Serve == nil && len(config.Hosts) == 0) {
+		return config, "", errors.New("config 'provider_options.domain.zone' and 'provider_options.domain.record' are required")
+	}
+
+	if config.Interval < 0 {
+		return config, "", errors.New("config 'interval' must not be negative")
+	}
+	if config.Health != nil {
+		// listen 为空时仅使用 systemd socket activation 传入的 socket
+		if config.Health.Listen != "" {
+			if _, _, err := net.SplitHostPort(config.Health.Listen); err != nil {
+				return config, "", errors.New("config 'health.listen' must be host:port, e.g. '127.0.0.1:8053'")
+			}
+		}
+		if config.Health.ReadyIntervals < 0 {
+			return config, "", errors.New("config 'health.ready_intervals' must not be negative")
+		}
+	}
+
+	if config.Notify != nil {
+		if err := validateNotify(config.Notify); err != nil {
+			return config, "", err
+		}
+	}
+
+	if config.Hooks != nil && config.Hooks.Timeout < 0 {
+		return config, "", errors.New("config 'hooks.timeout' must not be negative")
+	}
+
+	if config.Verify != nil && (config.Verify.Timeout < 0 || config.Verify.Interval < 0) {
+		return config, "", errors.New("config 'verify.timeout' and 'verify.interval' must not be negative")
+	}
+
+	if config.Drift != nil {
+		if config.Drift.Interval < 0 {
+			return config, "", errors.New("config 'drift_check.interval' must not be negative")
+		}
+		if config.Drift.Method != "" && config.Drift.Method != "api" && config.Drift.Method != "dns" {
+			return config, "", fmt.Errorf("unsupported drift_check.method '%s'. Supported: api, dns", config.Drift.Method)
+		}
+	}
+
+	if config.GetIP.Policy != nil {
+		if err := validatePolicy(config.GetIP.Policy); err != nil {
+			return config, "", err
+		}
+	}
+
+	if err := validateMirrors(config.Mirrors); err != nil {
+		return config, "", err
+	}
+	if err := validateHosts(config.Hosts, config.Options.Domain.Record); err != nil {
+		return config, "", err
+	}
+
+	if config.Serve != nil {
+		if err := validateServe(config.Serve); err != nil {
+			return config, "", err
+		}
 	}
 
 	changed := false
@@ -85,33 +469,329 @@ func ReadConfig(path string, quiet bool) (Config, string) {
 	if config.Proxy != "" {
 		pu, err := url.Parse(config.Proxy)
 		if err != nil || pu.Scheme == "" {
-			log.Fatal("Config 'proxy' must include scheme, e.g., 'socks5://127.0.0.1:1080' or 'http://127.0.0.1:8080'")
+			return config, "", errors.New("config 'proxy' must include scheme, e.g., 'socks5://127.0.0.1:1080' or 'http://127.0.0.1:8080'")
 		}
 		scheme := strings.ToLower(pu.Scheme)
 		if scheme != "http" && scheme != "https" && scheme != "socks5" && scheme != "socks5h" {
-			log.Fatal("Unsupported proxy scheme '%s' in config.proxy. Supported: http, https, socks5, socks5h", pu.Scheme)
+			return config, "", fmt.Errorf("unsupported proxy scheme '%s' in config.proxy. Supported: http, https, socks5, socks5h", pu.Scheme)
 		}
 		changed = true
 	}
-	if config.Cloudflare.TTL == 0 {
-		config.Cloudflare.TTL = 180
+	if config.Options.TTL == 0 {
+		config.Options.TTL = DefaultTTL(config.Provider)
 		changed = true
 	}
 	if config.WorkDir == "" {
 		config.WorkDir = ""
 		changed = true
 	}
-	if !config.Cloudflare.Proxied {
+	if !config.Options.Proxied {
 		changed = true
 	}
 
 	if changed {
-		if writeErr := WriteConfig(configFile, config); writeErr != nil {
+		stored := config
+		stored.Options.APIToken = fileToken
+		if stored.Options.RFC2136 != nil {
+			rfc := *stored.Options.RFC2136
+			rfc.KeySecret = fileSecret
+			stored.Options.RFC2136 = &rfc
+		}
+		if writeErr := WriteConfig(configFile, stored); writeErr != nil {
 			log.Error("Warning: Failed to standardize config file %s. Error: %v", configFile, writeErr)
 		}
 	}
 
-	return config, configFile
+	return config, configFile, nil
+}
+
+// validateProvider checks the provider_options needed by provider
+func validateProvider(provider string, o ProviderOptions) error {
+	switch provider {
+	case "cloudflare":
+		if o.APIToken == "" {
+			return errors.New("config 'provider_options.api_token' is required")
+		}
+	case "rfc2136":
+		return validateRFC2136(o.RFC2136)
+	case "digitalocean", "hetzner", "duckdns":
+		if o.APIToken == "" {
+			return errors.New("config 'provider_options.api_token' is required")
+		}
+	case "desec":
+		if o.APIToken == "" {
+			return errors.New("config 'provider_options.api_token' is required")
+		}
+		// deSEC 拒绝低于 3600 的 TTL
+		if o.TTL != 0 && o.TTL < 3600 {
+			return errors.New("config 'provider_options.ttl' must be at least 3600 for desec")
+		}
+	case "aliyun":
+		a := o.Aliyun
+		if a == nil || a.AccessKeyID == "" || a.AccessKeySecret == "" {
+			return errors.New("config 'provider_options.aliyun' needs 'access_key_id' and 'access_key_secret'")
+		}
+	case "dnspod":
+		d := o.DNSPod
+		if d == nil || d.SecretID == "" || d.SecretKey == "" {
+			return errors.New("config 'provider_options.dnspod' needs 'secret_id' and 'secret_key'")
+		}
+	case "powerdns":
+		if o.APIToken == "" {
+			return errors.New("config 'provider_options.api_token' is required")
+		}
+		if o.PowerDNS == nil {
+			return errors.New("config 'provider_options.powerdns.url' is required")
+		}
+		if u, err := url.Parse(o.PowerDNS.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("config 'provider_options.powerdns.url' must be an http(s) URL")
+		}
+	case "route53":
+		return validateRoute53(o.Route53)
+	case "dyndns2":
+		d := o.DynDNS2
+		if d == nil || d.Server == "" || d.Username == "" || d.Password == "" {
+			return errors.New("config 'provider_options.dyndns2' needs 'server', 'username' and 'password'")
+		}
+		if u, err := url.Parse(d.Server); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("config 'provider_options.dyndns2.server' must be an http(s) URL")
+		}
+	case "exec":
+		e := o.Exec
+		if e == nil || e.Command == "" {
+			return errors.New("config 'provider_options.exec.command' is required")
+		}
+		if e.Timeout < 0 {
+			return errors.New("config 'provider_options.exec.timeout' must not be negative")
+		}
+	default:
+		return fmt.Errorf("unsupported provider '%s'. Supported: cloudflare, rfc2136, powerdns, route53, dyndns2, digitalocean, hetzner, aliyun, dnspod, desec, duckdns, exec", provider)
+	}
+	if o.Endpoint != "" {
+		if u, err := url.Parse(o.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("config 'provider_options.endpoint' must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// mirrorNamePattern keeps target names usable in state file names. Names
+// cannot contain '.' or '@', which separate them from record names there.
+var mirrorNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validateMirrors(mirrors []MirrorTarget) error {
+	seen := map[string]bool{PrimaryTarget: true}
+	for i, m := range mirrors {
+		if !mirrorNamePattern.MatchString(m.Name) {
+			return fmt.Errorf("config 'mirrors[%d].name' is required and may only contain letters, digits, '-' and '_'", i)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("config 'mirrors[%d].name' '%s' is used twice or reserved", i, m.Name)
+		}
+		seen[m.Name] = true
+		if err := validateProvider(m.Provider, m.Options); err != nil {
+			return fmt.Errorf("mirror '%s': %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateHosts(hosts []HostConfig, mainRecord string) error {
+	seen := map[string]bool{mainRecord: true}
+	for i, h := range hosts {
+		if h.Record == "" {
+			return fmt.Errorf("config 'hosts[%d].record' is required", i)
+		}
+		if seen[h.Record] {
+			return fmt.Errorf("config 'hosts[%d].record' '%s' is used twice", i, h.Record)
+		}
+		seen[h.Record] = true
+		switch h.Source {
+		case "", "prefix":
+		case "ndp":
+			// 目前只有 Linux 能读取邻居表
+			if runtime.GOOS != "linux" {
+				return fmt.Errorf("config 'hosts[%d]' source 'ndp' is only supported on Linux", i)
+			}
+			if h.MAC == "" || h.Suffix != "" {
+				return fmt.Errorf("config 'hosts[%d]' with source 'ndp' needs 'mac' and no 'suffix'", i)
+			}
+		default:
+			return fmt.Errorf("unsupported hosts[%d].source '%s'. Supported: prefix, ndp", i, h.Source)
+		}
+		if (h.Suffix == "") == (h.MAC == "") {
+			return fmt.Errorf("config 'hosts[%d]' needs exactly one of 'suffix' and 'mac'", i)
+		}
+		if h.Suffix != "" {
+			if ip := net.ParseIP(h.Suffix); ip == nil || ip.To4() != nil {
+				return fmt.Errorf("config 'hosts[%d].suffix' must be an IPv6 interface identifier, e.g. '::10'", i)
+			}
+		}
+		if h.MAC != "" {
+			if mac, err := net.ParseMAC(h.MAC); err != nil || len(mac) != 6 {
+				return fmt.Errorf("config 'hosts[%d].mac' must be a 48-bit MAC address", i)
+			}
+		}
+		if h.PrefixLength < 0 || h.PrefixLength > 127 {
+			return fmt.Errorf("config 'hosts[%d].prefix_length' must be between 1 and 127", i)
+		}
+	}
+	return nil
+}
+
+func validatePolicy(p *SelectionPolicy) error {
+	for _, list := range []struct {
+		name  string
+		cidrs []string
+	}{{"allow", p.Allow}, {"deny", p.Deny}} {
+		for _, c := range list.cidrs {
+			if _, _, err := net.ParseCIDR(c); err != nil {
+				return fmt.Errorf("config 'get_ip.policy.%s' has invalid CIDR '%s'", list.name, c)
+			}
+		}
+	}
+	if p.Suffix != "" && p.Suffix != "eui64" {
+		if ip := net.ParseIP(p.Suffix); ip == nil || ip.To4() != nil {
+			return errors.New("config 'get_ip.policy.suffix' must be an IPv6 interface identifier, e.g. '::1', or 'eui64'")
+		}
+	}
+	switch p.PreferState {
+	case "", "static", "dynamic":
+	default:
+		return fmt.Errorf("unsupported get_ip.policy.prefer_state '%s'. Supported: static, dynamic", p.PreferState)
+	}
+	if p.MinLifetime < 0 || p.StickyMargin < 0 {
+		return errors.New("config 'get_ip.policy.min_lifetime' and 'get_ip.policy.sticky_margin' must not be negative")
+	}
+	seen := map[string]bool{}
+	for _, c := range p.Order {
+		if !slices.Contains(SelectionCriteria, c) {
+			return fmt.Errorf("unsupported get_ip.policy.order entry '%s'. Supported: %s", c, strings.Join(SelectionCriteria, ", "))
+		}
+		if seen[c] {
+			return fmt.Errorf("config 'get_ip.policy.order' lists '%s' twice", c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+func validateRFC2136(r *RFC2136Config) error {
+	if r == nil || r.Server == "" {
+		return errors.New("config 'provider_options.rfc2136.server' is required")
+	}
+	switch r.Transport {
+	case "", "udp", "tcp":
+	default:
+		return fmt.Errorf("unsupported provider_options.rfc2136.transport '%s'. Supported: udp, tcp", r.Transport)
+	}
+	switch r.Prerequisite {
+	case "", "name_in_use", "name_not_in_use", "rrset_exists", "rrset_not_exists":
+	default:
+		return fmt.Errorf("unsupported provider_options.rfc2136.prerequisite '%s'", r.Prerequisite)
+	}
+	if r.KeyName == "" {
+		return nil
+	}
+	switch strings.ToLower(r.KeyAlgorithm) {
+	case "", "hmac-sha256", "hmac-sha512":
+	default:
+		return fmt.Errorf("unsupported provider_options.rfc2136.key_algorithm '%s'. Supported: hmac-sha256, hmac-sha512", r.KeyAlgorithm)
+	}
+	if _, err := base64.StdEncoding.DecodeString(r.KeySecret); err != nil || r.KeySecret == "" {
+		return errors.New("config 'provider_options.rfc2136.key_secret' must be the base64 encoded TSIG secret")
+	}
+	return nil
+}
+
+func validateRoute53(r *Route53Config) error {
+	// 未配置时凭据全部来自环境变量或共享凭据文件
+	if r == nil {
+		return nil
+	}
+	if (r.AccessKeyID == "") != (r.SecretAccessKey == "") {
+		return errors.New("config 'provider_options.route53' needs both 'access_key_id' and 'secret_access_key'")
+	}
+	if r.Endpoint != "" {
+		if u, err := url.Parse(r.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("config 'provider_options.route53.endpoint' must be an http(s) URL")
+		}
+	}
+	if r.WaitTimeout < 0 {
+		return errors.New("config 'provider_options.route53.wait_timeout' must not be negative")
+	}
+	return nil
+}
+
+func validateServe(s *ServeConfig) error {
+	if s.Listen != "" {
+		if _, _, err := net.SplitHostPort(s.Listen); err != nil {
+			return errors.New("config 'serve.listen' must be host:port, e.g. ':8245'")
+		}
+	}
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return errors.New("config 'serve' needs both 'tls_cert' and 'tls_key'")
+	}
+	seen := map[string]bool{}
+	for i, c := range s.Clients {
+		if c.Username == "" || c.Password == "" || len(c.Hostnames) == 0 {
+			return fmt.Errorf("config 'serve.clients[%d]' needs 'username', 'password' and 'hostnames'", i)
+		}
+		if seen[c.Username] {
+			return fmt.Errorf("duplicate username '%s' in serve.clients", c.Username)
+		}
+		seen[c.Username] = true
+	}
+	return nil
+}
+
+func validateNotify(n *NotifyConfig) error {
+	if n.FailureThreshold < 0 {
+		return errors.New("config 'notify.failure_threshold' must not be negative")
+	}
+	for i, wh := range n.Webhooks {
+		u, err := url.Parse(wh.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("config 'notify.webhooks[%d].url' must be an http(s) URL", i)
+		}
+		switch wh.Format {
+		case "", "json", "slack", "discord", "ntfy", "gotify":
+		default:
+			return fmt.Errorf("unsupported format '%s' in notify.webhooks[%d]", wh.Format, i)
+		}
+		if wh.Retries != nil && *wh.Retries < 0 {
+			return fmt.Errorf("config 'notify.webhooks[%d].retries' must not be negative", i)
+		}
+		if err := validateEvents(wh.Events); err != nil {
+			return fmt.Errorf("notify.webhooks[%d]: %w", i, err)
+		}
+	}
+	if n.SMTP != nil {
+		if n.SMTP.Host == "" || n.SMTP.From == "" || len(n.SMTP.To) == 0 {
+			return errors.New("config 'notify.smtp' needs 'host', 'from' and 'to'")
+		}
+		switch n.SMTP.Security {
+		case "", "starttls", "tls", "none":
+		default:
+			return fmt.Errorf("unsupported notify.smtp.security '%s'. Supported: starttls, tls, none", n.SMTP.Security)
+		}
+		if n.SMTP.MinInterval < 0 {
+			return errors.New("config 'notify.smtp.min_interval' must not be negative")
+		}
+		if err := validateEvents(n.SMTP.Events); err != nil {
+			return fmt.Errorf("notify.smtp: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateEvents(events []string) error {
+	for _, ev := range events {
+		if ev != "change" && ev != "failure" && ev != "recovery" {
+			return fmt.Errorf("unknown event '%s'", ev)
+		}
+	}
+	return nil
 }
 
 // WriteConfig writes config to the given path
@@ -125,14 +805,20 @@ func WriteConfig(path string, config Config) error {
 
 // GetCacheFilePath returns the path for storing last ip
 func GetCacheFilePath(configFile string, workDir string) string {
+	return GetWorkFilePath(configFile, workDir, "cache.lastip")
+}
+
+// GetWorkFilePath returns the path of a state file kept in work_dir, or next
+// to the config file when work_dir is not set or cannot be created
+func GetWorkFilePath(configFile string, workDir string, name string) string {
 	if workDir != "" {
 		if err := os.MkdirAll(workDir, 0755); err != nil {
 			log.Error("Warning: Failed to create work_dir '%s'. Falling back to config file directory. Error: %v", workDir, err)
-			return filepath.Join(filepath.Dir(configFile), "cache.lastip")
+			return filepath.Join(filepath.Dir(configFile), name)
 		}
-		return filepath.Join(workDir, "cache.lastip")
+		return filepath.Join(workDir, name)
 	}
-	return filepath.Join(filepath.Dir(configFile), "cache.lastip")
+	return filepath.Join(filepath.Dir(configFile), name)
 }
 
 // ReadLastIP reads the last IP from cache file
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// checkValidation compares err with want, a substring of the expected error
+// or "" when the input is valid
+func checkValidation(t *testing.T, name string, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("%s: unexpected error %v", name, err)
+	case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+		t.Errorf("%s: error %v, want it to contain %q", name, err, want)
+	}
+}
+
+func mirror(name string) MirrorTarget {
+	m := MirrorTarget{Name: name, Provider: "cloudflare"}
+	m.Options.APIToken = "token"
+	return m
+}
+
+func TestValidateMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors []MirrorTarget
+		want    string
+	}{
+		{"none", nil, ""},
+		{"valid", []MirrorTarget{mirror("backup"), mirror("Secondary_2-a")}, ""},
+		{"empty name", []MirrorTarget{mirror("")}, "'mirrors[0].name' is required"},
+		// '.' 与 '@' 用于分隔缓存文件名中的记录名与镜像名
+		{"dot", []MirrorTarget{mirror("backup.example")}, "may only contain"},
+		{"at", []MirrorTarget{mirror("backup@home")}, "may only contain"},
+		{"slash", []MirrorTarget{mirror("../backup")}, "may only contain"},
+		{"reserved", []MirrorTarget{mirror(PrimaryTarget)}, "used twice or reserved"},
+		{"duplicate", []MirrorTarget{mirror("backup"), mirror("backup")}, "'mirrors[1].name' 'backup' is used twice"},
+		{"unknown provider", []MirrorTarget{{Name: "backup", Provider: "nope"}}, "mirror 'backup': unsupported provider"},
+		{"missing credentials", []MirrorTarget{{Name: "backup", Provider: "cloudflare"}}, "mirror 'backup': config 'provider_options.api_token' is required"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validateMirrors(tt.mirrors), tt.want)
+	}
+}
+
+func TestLoadGetIP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	// 没有服务商凭据，LoadConfig 会拒绝
+	data := `{"provider": "cloudflare", "get_ip": {"interface": "eth0", "policy": {"min_lifetime": 600}}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, configFile, err := LoadGetIP(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configFile != path || cfg.GetIP.Interface != "eth0" || cfg.GetIP.Policy == nil || cfg.GetIP.Policy.MinLifetime != 600 {
+		t.Errorf("unexpected result %q %+v", configFile, cfg.GetIP)
+	}
+	if got, _ := os.ReadFile(path); string(got) != data {
+		t.Errorf("config file was rewritten: %s", got)
+	}
+
+	if _, _, err := LoadGetIP(filepath.Join(dir, "missing.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: error %v, want fs.ErrNotExist", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"get_ip": {"policy": {"prefer_state": "sometimes"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadGetIP(path); err == nil || !strings.Contains(err.Error(), "prefer_state") {
+		t.Errorf("invalid policy: error %v", err)
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	zero, negative := 0, -1
+	tests := []struct {
+		name   string
+		notify NotifyConfig
+		want   string
+	}{
+		{"empty", NotifyConfig{}, ""},
+		{"negative threshold", NotifyConfig{FailureThreshold: -1}, "failure_threshold"},
+		{"webhook", NotifyConfig{Webhooks: []WebhookConfig{{URL: "https://hooks.example.com/x", Format: "slack", Events: []string{"change", "recovery"}}}}, ""},
+		{"webhook without retries", NotifyConfig{Webhooks: []WebhookConfig{{URL: "https://hooks.example.com/x", Retries: &zero}}}, ""},
+		{"negative retries", NotifyConfig{Webhooks: []WebhookConfig{{URL: "https://hooks.example.com/x", Retries: &negative}}}, "'notify.webhooks[0].retries' must not be negative"},
+		{"bad url", NotifyConfig{Webhooks: []WebhookConfig{{URL: "ftp://hooks.example.com"}}}, "'notify.webhooks[0].url' must be an http(s) URL"},
+		{"bad format", NotifyConfig{Webhooks: []WebhookConfig{{URL: "https://hooks.example.com", Format: "teams"}}}, "unsupported format 'teams'"},
+		{"bad event", NotifyConfig{Webhooks: []WebhookConfig{{URL: "https://hooks.example.com", Events: []string{"boot"}}}}, "unknown event 'boot'"},
+		{"smtp", NotifyConfig{SMTP: &SMTPConfig{Host: "mail.example.com", Security: "tls", From: "goddns@example.com", To: []string{"admin@example.com"}}}, ""},
+		{"smtp without recipients", NotifyConfig{SMTP: &SMTPConfig{Host: "mail.example.com", From: "goddns@example.com"}}, "'notify.smtp' needs 'host', 'from' and 'to'"},
+		{"smtp bad security", NotifyConfig{SMTP: &SMTPConfig{Host: "mail.example.com", Security: "ssl", From: "goddns@example.com", To: []string{"admin@example.com"}}}, "unsupported notify.smtp.security 'ssl'"},
+		{"smtp negative interval", NotifyConfig{SMTP: &SMTPConfig{Host: "mail.example.com", From: "goddns@example.com", To: []string{"admin@example.com"}, MinInterval: -1}}, "min_interval"},
+		{"smtp bad event", NotifyConfig{SMTP: &SMTPConfig{Host: "mail.example.com", From: "goddns@example.com", To: []string{"admin@example.com"}, Events: []string{"change", "boot"}}}, "notify.smtp: unknown event 'boot'"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validateNotify(&tt.notify), tt.want)
+	}
+}
+
+func TestValidateRFC2136(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *RFC2136Config
+		want string
+	}{
+		{"missing", nil, "'provider_options.rfc2136.server' is required"},
+		{"unsigned", &RFC2136Config{Server: "ns1.example.com"}, ""},
+		{"signed", &RFC2136Config{Server: "ns1.example.com:53", Transport: "tcp", KeyName: "goddns.", KeyAlgorithm: "HMAC-SHA512", KeySecret: "c2VjcmV0", Prerequisite: "rrset_exists"}, ""},
+		{"bad transport", &RFC2136Config{Server: "ns1.example.com", Transport: "tls"}, "unsupported provider_options.rfc2136.transport 'tls'"},
+		{"bad prerequisite", &RFC2136Config{Server: "ns1.example.com", Prerequisite: "zone_exists"}, "unsupported provider_options.rfc2136.prerequisite 'zone_exists'"},
+		{"bad algorithm", &RFC2136Config{Server: "ns1.example.com", KeyName: "goddns.", KeyAlgorithm: "hmac-md5", KeySecret: "c2VjcmV0"}, "unsupported provider_options.rfc2136.key_algorithm 'hmac-md5'"},
+		{"missing secret", &RFC2136Config{Server: "ns1.example.com", KeyName: "goddns."}, "key_secret' must be the base64 encoded TSIG secret"},
+		{"bad secret", &RFC2136Config{Server: "ns1.example.com", KeyName: "goddns.", KeySecret: "not base64!"}, "key_secret' must be the base64 encoded TSIG secret"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validateRFC2136(tt.cfg), tt.want)
+	}
+}
+
+func TestValidateRoute53(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Route53Config
+		want string
+	}{
+		// 未配置时凭据来自环境变量或共享凭据文件
+		{"missing", nil, ""},
+		{"profile", &Route53Config{Profile: "dns", Region: "eu-west-1"}, ""},
+		{"keys", &Route53Config{AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: "http://127.0.0.1:4566", WaitTimeout: 60}, ""},
+		{"half a key", &Route53Config{AccessKeyID: "AKID"}, "needs both 'access_key_id' and 'secret_access_key'"},
+		{"bad endpoint", &Route53Config{Endpoint: "route53.amazonaws.com"}, "'provider_options.route53.endpoint' must be an http(s) URL"},
+		{"negative wait", &Route53Config{WaitTimeout: -1}, "wait_timeout' must not be negative"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validateRoute53(tt.cfg), tt.want)
+	}
+}
+
+func TestValidateServe(t *testing.T) {
+	client := ServeClient{Username: "router", Password: "secret", Hostnames: []string{"*.example.com"}}
+	tests := []struct {
+		name  string
+		serve ServeConfig
+		want  string
+	}{
+		{"valid", ServeConfig{Listen: ":8245", Clients: []ServeClient{client}}, ""},
+		{"tls", ServeConfig{Listen: "[::]:8245", TLSCert: "cert.pem", TLSKey: "key.pem", Clients: []ServeClient{client}}, ""},
+		{"bad listen", ServeConfig{Listen: "8245"}, "'serve.listen' must be host:port"},
+		{"cert without key", ServeConfig{TLSCert: "cert.pem"}, "needs both 'tls_cert' and 'tls_key'"},
+		{"no hostnames", ServeConfig{Clients: []ServeClient{{Username: "router", Password: "secret"}}}, "'serve.clients[0]' needs 'username', 'password' and 'hostnames'"},
+		{"no password", ServeConfig{Clients: []ServeClient{{Username: "router", Hostnames: []string{"home.example.com"}}}}, "'serve.clients[0]' needs"},
+		{"duplicate user", ServeConfig{Clients: []ServeClient{client, client}}, "duplicate username 'router'"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validateServe(&tt.serve), tt.want)
+	}
+}
+
+func TestValidateHosts(t *testing.T) {
+	// 其他系统上 ndp 来源一律拒绝
+	ndp := func(want string) string {
+		if runtime.GOOS != "linux" {
+			return "source 'ndp' is only supported on Linux"
+		}
+		return want
+	}
+	tests := []struct {
+		name  string
+		hosts []HostConfig
+		want  string
+	}{
+		{"none", nil, ""},
+		{"suffix and mac", []HostConfig{{Record: "nas", Suffix: "::10"}, {Record: "printer", MAC: "52:54:00:12:34:56", PrefixLength: 56}}, ""},
+		{"no record", []HostConfig{{Suffix: "::10"}}, "'hosts[0].record' is required"},
+		{"main record", []HostConfig{{Record: "home", Suffix: "::10"}}, "'hosts[0].record' 'home' is used twice"},
+		{"duplicate", []HostConfig{{Record: "nas", Suffix: "::10"}, {Record: "nas", Suffix: "::11"}}, "'hosts[1].record' 'nas' is used twice"},
+		{"neither", []HostConfig{{Record: "nas"}}, "needs exactly one of 'suffix' and 'mac'"},
+		{"both", []HostConfig{{Record: "nas", Suffix: "::10", MAC: "52:54:00:12:34:56"}}, "needs exactly one of 'suffix' and 'mac'"},
+		{"ipv4 suffix", []HostConfig{{Record: "nas", Suffix: "10.0.0.1"}}, "'hosts[0].suffix' must be an IPv6 interface identifier"},
+		{"bad suffix", []HostConfig{{Record: "nas", Suffix: "10"}}, "'hosts[0].suffix' must be an IPv6 interface identifier"},
+		{"eui-64 mac", []HostConfig{{Record: "nas", MAC: "52:54:00:ff:fe:12:34:56"}}, "'hosts[0].mac' must be a 48-bit MAC address"},
+		{"prefix too long", []HostConfig{{Record: "nas", Suffix: "::10", PrefixLength: 128}}, "prefix_length' must be between 1 and 127"},
+		{"ndp", []HostConfig{{Record: "nas", Source: "ndp", MAC: "52:54:00:12:34:56"}}, ndp("")},
+		{"ndp with suffix", []HostConfig{{Record: "nas", Source: "ndp", Suffix: "::10"}}, ndp("with source 'ndp' needs 'mac' and no 'suffix'")},
+		{"bad source", []HostConfig{{Record: "nas", Source: "dhcp", Suffix: "::10"}}, "unsupported hosts[0].source 'dhcp'"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validateHosts(tt.hosts, "home"), tt.want)
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SelectionPolicy
+		want   string
+	}{
+		{"empty", SelectionPolicy{}, ""},
+		{"full", SelectionPolicy{Allow: []string{"2001:db8::/32"}, Deny: []string{"2001:db8:0:ff::/64"}, Suffix: "::1", PreferState: "static", MinLifetime: 600, Order: []string{"lifetime", "suffix"}}, ""},
+		{"eui64", SelectionPolicy{Suffix: "eui64", PreferState: "dynamic"}, ""},
+		{"bad allow", SelectionPolicy{Allow: []string{"2001:db8::"}}, "'get_ip.policy.allow' has invalid CIDR '2001:db8::'"},
+		{"bad deny", SelectionPolicy{Deny: []string{"fd00::/129"}}, "'get_ip.policy.deny' has invalid CIDR"},
+		{"ipv4 suffix", SelectionPolicy{Suffix: "0.0.0.1"}, "'get_ip.policy.suffix' must be an IPv6 interface identifier"},
+		{"bad prefer_state", SelectionPolicy{PreferState: "temporary"}, "unsupported get_ip.policy.prefer_state 'temporary'"},
+		{"negative lifetime", SelectionPolicy{MinLifetime: -1}, "get_ip.policy.min_lifetime'"},
+		{"sticky", SelectionPolicy{Sticky: true, StickyMargin: 3600}, ""},
+		{"negative sticky margin", SelectionPolicy{Sticky: true, StickyMargin: -1}, "'get_ip.policy.sticky_margin' must not be negative"},
+		{"unknown criterion", SelectionPolicy{Order: []string{"suffix", "age"}}, "unsupported get_ip.policy.order entry 'age'"},
+		{"repeated criterion", SelectionPolicy{Order: []string{"state", "state"}}, "lists 'state' twice"},
+	}
+	for _, tt := range tests {
+		checkValidation(t, tt.name, validatePolicy(&tt.policy), tt.want)
+	}
+}
@@ -0,0 +1,153 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goddns/internal/log"
+	"goddns/internal/updater"
+)
+
+// Commands understood by the control socket
+const (
+	CmdUpdate = "update"
+	CmdReload = "reload"
+	CmdPause  = "pause"
+	CmdResume = "resume"
+	CmdStatus = "status"
+)
+
+// Request is one line of JSON sent by the client
+type Request struct {
+	Command string `json:"command"`
+	Record  string `json:"record,omitempty"`
+}
+
+// Response is one line of JSON sent back by the server
+type Response struct {
+	OK     bool            `json:"ok"`
+	Error  string          `json:"error,omitempty"`
+	Status *updater.Status `json:"status,omitempty"`
+}
+
+// Server accepts control requests on a Unix domain socket
+type Server struct {
+	updater  *updater.Updater
+	listener net.Listener
+}
+
+// NewServer constructor
+func NewServer(u *updater.Updater) *Server {
+	return &Server{updater: u}
+}
+
+// Listen creates the socket at path, replacing a stale one
+func (s *Server) Listen(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return err
+	}
+	s.listener = l
+	return nil
+}
+
+// SetListener uses an already open listener, e.g. from socket activation
+func (s *Server) SetListener(l net.Listener) {
+	s.listener = l
+}
+
+// Serve handles connections until Close
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting connections and removes the socket
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: "invalid request: " + err.Error()})
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	resp := s.dispatch(req)
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) dispatch(req Request) Response {
+	var err error
+	switch req.Command {
+	case CmdUpdate:
+		log.Info("Forced update requested via control socket")
+		err = s.updater.Sync(true)
+	case CmdReload:
+		err = s.updater.Reload()
+	case CmdPause, CmdResume:
+		err = s.updater.SetPaused(req.Record, req.Command == CmdPause)
+		if err == nil {
+			log.Info("Record %s %sd via control socket", req.Record, req.Command)
+		}
+	case CmdStatus:
+	default:
+		err = fmt.Errorf("unknown command '%s'", req.Command)
+	}
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	st := s.updater.Status()
+	return Response{OK: true, Status: &st}
+}
+
+// Send sends req to the daemon listening on path and returns its response
+func Send(path string, req Request) (Response, error) {
+	var resp Response
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return resp, fmt.Errorf("failed to connect to %s: %w", path, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, fmt.Errorf("failed to send request: %w", err)
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp, nil
+}
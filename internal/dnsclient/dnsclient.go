@@ -0,0 +1,215 @@
+package dnsclient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DefaultTimeout bounds a single exchange with one server
+const DefaultTimeout = 5 * time.Second
+
+// Fqdn returns name with a trailing dot
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// ServerAddr appends the DNS port to host when it has none
+func ServerAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), "53")
+}
+
+// Exchange sends a packed message to server and returns the packed reply.
+// UDP replies with the TC bit set are retried over TCP.
+func Exchange(ctx context.Context, server string, msg []byte, useTCP bool) ([]byte, error) {
+	if !useTCP {
+		reply, err := exchangeUDP(ctx, server, msg)
+		if err != nil {
+			return nil, err
+		}
+		// TC 标志位：响应被截断，改用 TCP
+		if len(reply) < 3 || reply[2]&0x02 == 0 {
+			return reply, nil
+		}
+	}
+	return exchangeTCP(ctx, server, msg)
+}
+
+func exchangeUDP(ctx context.Context, server string, msg []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline(ctx))
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// 丢弃 ID 不匹配的响应
+		if n >= 2 && buf[0] == msg[0] && buf[1] == msg[1] {
+			return buf[:n], nil
+		}
+	}
+}
+
+func exchangeTCP(ctx context.Context, server string, msg []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline(ctx))
+
+	out := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(out, uint16(len(msg)))
+	copy(out[2:], msg)
+	if _, err := conn.Write(out); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func deadline(ctx context.Context) time.Time {
+	if d, ok := ctx.Deadline(); ok {
+		return d
+	}
+	return time.Now().Add(DefaultTimeout)
+}
+
+// NewID returns a random message ID
+func NewID() uint16 {
+	return uint16(rand.Uint32())
+}
+
+// LookupAAAA asks server for the AAAA records of name. recursive sets the RD
+// bit, which should be off for authoritative servers.
+func LookupAAAA(ctx context.Context, server, name string, recursive bool) ([]string, error) {
+	qname, err := dnsmessage.NewName(Fqdn(name))
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: NewID(), RecursionDesired: recursive},
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := Exchange(ctx, ServerAddr(server), packed, false)
+	if err != nil {
+		return nil, err
+	}
+	var reply dnsmessage.Message
+	if err := reply.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("invalid reply from %s: %w", server, err)
+	}
+	if reply.RCode == dnsmessage.RCodeNameError {
+		return nil, nil
+	}
+	if reply.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("%s answered %s", server, reply.RCode)
+	}
+
+	var ips []string
+	for _, ans := range reply.Answers {
+		if r, ok := ans.Body.(*dnsmessage.AAAAResource); ok {
+			ips = append(ips, net.IP(r.AAAA[:]).String())
+		}
+	}
+	return ips, nil
+}
+
+// AuthoritativeServers returns the nameservers of zone as host -> addresses
+// ("ip:53"), using the system resolver
+func AuthoritativeServers(ctx context.Context, zone string) (map[string][]string, error) {
+	nss, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up NS records of %s: %w", zone, err)
+	}
+	if len(nss) == 0 {
+		return nil, fmt.Errorf("zone %s has no NS records", zone)
+	}
+
+	servers := make(map[string][]string, len(nss))
+	for _, ns := range nss {
+		host := strings.TrimSuffix(ns.Host, ".")
+		addrs, err := ResolveServer(ctx, host)
+		if err != nil {
+			continue
+		}
+		servers[host] = addrs
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("none of the nameservers of " + zone + " could be resolved")
+	}
+	return servers, nil
+}
+
+// ResolveServer turns "host", "host:port" or an IP into "ip:port" addresses
+func ResolveServer(ctx context.Context, server string) ([]string, error) {
+	host, port, err := net.SplitHostPort(ServerAddr(server))
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{net.JoinHostPort(host, port)}, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip.IP.String(), port))
+	}
+	return addrs, nil
+}
+
+// LookupAAAAAny tries addrs in order and returns the first answer
+func LookupAAAAAny(ctx context.Context, addrs []string, name string, recursive bool) ([]string, error) {
+	var lastErr error = errors.New("no server address")
+	for _, addr := range addrs {
+		ips, err := LookupAAAA(ctx, addr, name, recursive)
+		if err == nil {
+			return ips, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,345 @@
+package dyndns
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/hooks"
+	"goddns/internal/log"
+	"goddns/internal/notify"
+	"goddns/internal/provider"
+)
+
+// DefaultListen is used when 'serve.listen' is not set
+const DefaultListen = ":8245"
+
+// maxHostnames is the most hostnames accepted in one request, as with dyn.com
+const maxHostnames = 20
+
+// Server accepts dyndns2 /nic/update requests and relays the pushed address
+// to the configured provider
+type Server struct {
+	cfg        config.Config
+	configFile string
+	notifier   *notify.Dispatcher
+	srv        *http.Server
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState serializes the updates of one hostname so a slow upstream only
+// delays pushes for that name
+type hostState struct {
+	mu      sync.Mutex
+	targets []target
+}
+
+// target is the primary provider or a mirror of one hostname
+type target struct {
+	name      string
+	provider  provider.Provider
+	cacheFile string
+}
+
+// NewServer constructor
+func NewServer(cfg config.Config, configFile string) (*Server, error) {
+	notifier, err := notify.NewDispatcher(cfg, config.GetWorkFilePath(configFile, cfg.WorkDir, "notify.serve.state"))
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:        cfg,
+		configFile: configFile,
+		notifier:   notifier,
+		hosts:      make(map[string]*hostState),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nic/update", s.handleUpdate)
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s, nil
+}
+
+// Addr returns the configured listen address
+func (s *Server) Addr() string {
+	if s.cfg.Serve.Listen != "" {
+		return s.cfg.Serve.Listen
+	}
+	return DefaultListen
+}
+
+// ListenAndServe listens on the configured address and serves until Shutdown
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr())
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves requests on l until Shutdown, using TLS when configured
+func (s *Server) Serve(l net.Listener) error {
+	var err error
+	if s.cfg.Serve.TLSCert != "" {
+		err = s.srv.ServeTLS(l, s.cfg.Serve.TLSCert, s.cfg.Serve.TLSKey)
+	} else {
+		err = s.srv.Serve(l)
+	}
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server gracefully and waits for pending notifications
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	s.notifier.Wait()
+	return err
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="goddns"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("badauth\n"))
+		return
+	}
+	client := s.authenticate(user, pass)
+	if client == nil {
+		log.Warning("dyndns2: rejected credentials for '%s' from %s", user, r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("badauth\n"))
+		return
+	}
+
+	var hostnames []string
+	for _, h := range strings.Split(r.FormValue("hostname"), ",") {
+		if h = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(h), ".")); h != "" {
+			hostnames = append(hostnames, h)
+		}
+	}
+	if len(hostnames) == 0 {
+		w.Write([]byte("notfqdn\n"))
+		return
+	}
+	if len(hostnames) > maxHostnames {
+		w.Write([]byte("numhost\n"))
+		return
+	}
+
+	ip, ignored := pushedAddress(r)
+	if ip == "" {
+		// 只管理 AAAA 记录，IPv4 地址不做处理
+		log.Warning("dyndns2: %s pushed no IPv6 address (got '%s'), nothing to update", user, ignored)
+	}
+
+	lines := make([]string, 0, len(hostnames))
+	for _, host := range hostnames {
+		lines = append(lines, s.update(client, host, ip, ignored))
+	}
+	w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+}
+
+// authenticate returns the client with the given credentials, or nil
+func (s *Server) authenticate(user, pass string) *config.ServeClient {
+	for i, c := range s.cfg.Serve.Clients {
+		userOK := subtle.ConstantTimeCompare([]byte(c.Username), []byte(user)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(c.Password), []byte(pass)) == 1
+		if userOK && passOK {
+			return &s.cfg.Serve.Clients[i]
+		}
+	}
+	return nil
+}
+
+// pushedAddress returns the IPv6 address from myipv6, ipv6 or myip, falling
+// back to the address of the client when none is given. The second value is
+// an address that was pushed but is not IPv6.
+func pushedAddress(r *http.Request) (string, string) {
+	ignored := ""
+	given := false
+	for _, param := range []string{"myipv6", "ipv6", "myip"} {
+		for _, v := range strings.Split(r.FormValue(param), ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			given = true
+			if ip := net.ParseIP(v); ip != nil && ip.To4() == nil {
+				return ip.String(), ""
+			}
+			ignored = v
+		}
+	}
+	if !given {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err == nil && ip != nil {
+			if ip.To4() == nil {
+				return ip.String(), ""
+			}
+			ignored = ip.String()
+		}
+	}
+	return "", ignored
+}
+
+// hostnamePattern matches names made of LDH labels (letters, digits,
+// hyphens) separated by dots
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// validHostname reports whether host is a lower-case DNS name that is safe to
+// use in state file names
+func validHostname(host string) bool {
+	return len(host) <= 253 && hostnamePattern.MatchString(host)
+}
+
+// allowed reports whether client may update host
+func allowed(client *config.ServeClient, host string) bool {
+	for _, pattern := range client.Hostnames {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+		if pattern == host {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// update publishes ip for host and returns the dyndns2 return code
+func (s *Server) update(client *config.ServeClient, host, ip, ignored string) string {
+	if !strings.Contains(host, ".") || !validHostname(host) {
+		return "notfqdn"
+	}
+	zone := strings.ToLower(strings.TrimSuffix(s.cfg.Options.Domain.Zone, "."))
+	record := config.ApexRecord
+	if host != zone {
+		if !strings.HasSuffix(host, "."+zone) {
+			record = ""
+		} else {
+			record = strings.TrimSuffix(host, "."+zone)
+		}
+	}
+	if !allowed(client, host) || record == "" {
+		log.Warning("dyndns2: %s is not allowed to update %s", client.Username, host)
+		return "nohost"
+	}
+	if ip == "" {
+		return "nochg " + ignored
+	}
+
+	hs, err := s.host(host, record)
+	if err != nil {
+		log.Error("dyndns2: failed to update %s: %v", host, err)
+		s.notifier.Result(err)
+		return "dnserr"
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	cached := make([]string, len(hs.targets))
+	pending := false
+	for i, t := range hs.targets {
+		cached[i] = config.ReadLastIP(t.cacheFile)
+		pending = pending || cached[i] != ip
+	}
+	if !pending {
+		log.Info("dyndns2: %s unchanged (%s), pushed by %s", host, ip, client.Username)
+		return "nochg " + ip
+	}
+
+	runner := hooks.NewRunner(s.cfg.Hooks)
+	env := hooks.Env{Record: host, OldIP: cached[0], NewIP: ip, Result: "pending"}
+	err = runner.Run(hooks.PreUpdate, env)
+	if err == nil {
+		// 每个目标单独缓存，失败的目标在下次推送时单独重试
+		var errs []error
+		for i, t := range hs.targets {
+			if cached[i] == ip {
+				continue
+			}
+			if err := t.provider.UpsertRecord(ip); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+				continue
+			}
+			if err := config.WriteLastIP(t.cacheFile, ip); err != nil {
+				log.Warning("Failed to write cache file %s: %v", t.cacheFile, err)
+			}
+		}
+		err = errors.Join(errs...)
+	}
+	if cached[0] != ip && config.ReadLastIP(hs.targets[0].cacheFile) == ip {
+		log.Success("DNS record %s updated to %s (pushed by %s)", host, ip, client.Username)
+		s.notifier.Changed(host, cached[0], ip)
+	}
+	s.notifier.Result(err)
+	if err != nil {
+		log.Error("dyndns2: failed to update %s: %v", host, err)
+		env.Result, env.Error = "failure", err.Error()
+		if err := runner.Run(hooks.OnFailure, env); err != nil {
+			log.Error("%v", err)
+		}
+		return "dnserr"
+	}
+
+	env.Result = "success"
+	if err := runner.Run(hooks.PostUpdate, env); err != nil {
+		log.Error("%v", err)
+	}
+	return "good " + ip
+}
+
+// host returns the state of host, creating its providers on first use. Cache
+// and provider state files carry the (validated) hostname; mirror files add
+// '@' and the mirror name, which cannot occur in a hostname.
+func (s *Server) host(host, record string) (*hostState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hs, ok := s.hosts[host]; ok {
+		return hs, nil
+	}
+
+	cfg := s.cfg
+	cfg.Options.Domain.Record = record
+	p, err := provider.NewTarget(cfg, s.configFile, "serve."+host+".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	hs := &hostState{targets: []target{{
+		name:      config.PrimaryTarget,
+		provider:  p,
+		cacheFile: config.GetWorkFilePath(s.configFile, cfg.WorkDir, "serve."+host+".lastip"),
+	}}}
+	for _, m := range cfg.Mirrors {
+		mcfg := cfg.MirrorConfig(m)
+		mcfg.Options.Domain.Record = record
+		p, err := provider.NewTarget(mcfg, s.configFile, "serve."+host+"@"+m.Name+".")
+		if err != nil {
+			return nil, fmt.Errorf("mirror '%s': failed to create provider: %w", m.Name, err)
+		}
+		hs.targets = append(hs.targets, target{
+			name:      m.Name,
+			provider:  p,
+			cacheFile: config.GetWorkFilePath(s.configFile, cfg.WorkDir, "serve."+host+"@"+m.Name+".lastip"),
+		})
+	}
+	s.hosts[host] = hs
+	return hs, nil
+}
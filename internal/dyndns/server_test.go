@@ -0,0 +1,184 @@
+package dyndns
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeProvider records the addresses it was asked to publish
+type fakeProvider struct {
+	mu  sync.Mutex
+	ips []string
+	err error
+}
+
+func (p *fakeProvider) GetRecord() ([]string, error) { return nil, nil }
+
+func (p *fakeProvider) UpsertRecord(ip string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.ips = append(p.ips, ip)
+	return nil
+}
+
+func (p *fakeProvider) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.ips...)
+}
+
+func newTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	cfg := config.Config{Provider: "exec"}
+	cfg.Options.Domain.Zone = "example.com"
+	cfg.Options.Exec = &config.ExecConfig{Command: "/bin/true"}
+	cfg.Serve = &config.ServeConfig{Clients: []config.ServeClient{
+		{Username: "router", Password: "secret", Hostnames: []string{"home.example.com", "*.lab.example.com", "example.com", "home.example.org"}},
+	}}
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	s, err := NewServer(cfg, configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, configFile
+}
+
+// withFake makes host publish through p instead of the configured provider
+func (s *Server) withFake(host, configFile string, p *fakeProvider) {
+	s.hosts[host] = &hostState{targets: []target{{
+		name:      config.PrimaryTarget,
+		provider:  p,
+		cacheFile: filepath.Join(filepath.Dir(configFile), "serve."+host+".lastip"),
+	}}}
+}
+
+func push(s *Server, user, pass string, query url.Values) (int, string) {
+	r := httptest.NewRequest("GET", "/nic/update?"+query.Encode(), nil)
+	r.RemoteAddr = "[2001:db8::99]:40000"
+	if user != "" {
+		r.SetBasicAuth(user, pass)
+	}
+	w := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(w, r)
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestServeAuth(t *testing.T) {
+	s, _ := newTestServer(t)
+	q := url.Values{"hostname": {"home.example.com"}, "myipv6": {"2001:db8::1"}}
+
+	if code, body := push(s, "", "", q); code != http.StatusUnauthorized || body != "badauth" {
+		t.Errorf("no credentials: %d %q", code, body)
+	}
+	if code, body := push(s, "router", "wrong", q); code != http.StatusUnauthorized || body != "badauth" {
+		t.Errorf("wrong password: %d %q", code, body)
+	}
+	if code, body := push(s, "nobody", "secret", q); code != http.StatusUnauthorized || body != "badauth" {
+		t.Errorf("unknown user: %d %q", code, body)
+	}
+}
+
+func TestServeReturnCodes(t *testing.T) {
+	tests := []struct {
+		hostname string
+		myip     string
+		want     string
+	}{
+		{"", "2001:db8::1", "notfqdn"},
+		{"home", "2001:db8::1", "notfqdn"},
+		{"bad_name.example.com", "2001:db8::1", "notfqdn"},
+		{"-home.example.com", "2001:db8::1", "notfqdn"},
+		{strings.Repeat("a", 64) + ".example.com", "2001:db8::1", "notfqdn"},
+		{strings.TrimSuffix(strings.Repeat("home.example.com,", maxHostnames+1), ","), "2001:db8::1", "numhost"},
+		// 不在白名单中
+		{"other.example.com", "2001:db8::1", "nohost"},
+		{"lab.example.com", "2001:db8::1", "nohost"},
+		// 在白名单中但不在区域内
+		{"home.example.org", "2001:db8::1", "nohost"},
+		// 只管理 AAAA 记录
+		{"home.example.com", "192.0.2.1", "nochg 192.0.2.1"},
+	}
+	for _, tt := range tests {
+		s, configFile := newTestServer(t)
+		p := &fakeProvider{}
+		s.withFake("home.example.com", configFile, p)
+		code, body := push(s, "router", "secret", url.Values{"hostname": {tt.hostname}, "myip": {tt.myip}})
+		if code != http.StatusOK || body != tt.want {
+			t.Errorf("hostname %q myip %q: %d %q, want %q", tt.hostname, tt.myip, code, body, tt.want)
+		}
+		if got := p.published(); len(got) != 0 {
+			t.Errorf("hostname %q myip %q: published %v", tt.hostname, tt.myip, got)
+		}
+	}
+}
+
+func TestServeUpdate(t *testing.T) {
+	s, configFile := newTestServer(t)
+	home, lab := &fakeProvider{}, &fakeProvider{}
+	s.withFake("home.example.com", configFile, home)
+	s.withFake("a.lab.example.com", configFile, lab)
+
+	q := url.Values{"hostname": {"Home.Example.com.,a.lab.example.com"}, "myipv6": {"2001:db8::1"}}
+	if code, body := push(s, "router", "secret", q); code != http.StatusOK || body != "good 2001:db8::1\ngood 2001:db8::1" {
+		t.Fatalf("first push: %d %q", code, body)
+	}
+	if code, body := push(s, "router", "secret", q); body != "nochg 2001:db8::1\nnochg 2001:db8::1" {
+		t.Fatalf("repeated push: %d %q", code, body)
+	}
+	// 未提供地址时使用请求来源地址
+	if _, body := push(s, "router", "secret", url.Values{"hostname": {"home.example.com"}}); body != "good 2001:db8::99" {
+		t.Fatalf("push without address: %q", body)
+	}
+	if got := home.published(); len(got) != 2 || got[0] != "2001:db8::1" || got[1] != "2001:db8::99" {
+		t.Errorf("home.example.com published %v", got)
+	}
+	if got := lab.published(); len(got) != 1 {
+		t.Errorf("a.lab.example.com published %v", got)
+	}
+
+	home.err = errors.New("upstream down")
+	if _, body := push(s, "router", "secret", url.Values{"hostname": {"home.example.com"}, "myipv6": {"2001:db8::2"}}); body != "dnserr" {
+		t.Errorf("failed update: %q", body)
+	}
+	// 失败的更新不写缓存，下次推送重试
+	home.err = nil
+	if _, body := push(s, "router", "secret", url.Values{"hostname": {"home.example.com"}, "myipv6": {"2001:db8::2"}}); body != "good 2001:db8::2" {
+		t.Errorf("retry after failure: %q", body)
+	}
+}
+
+func TestServeTargetFiles(t *testing.T) {
+	s, configFile := newTestServer(t)
+	mirror := config.MirrorTarget{Name: "backup", Provider: "exec"}
+	mirror.Options.Exec = &config.ExecConfig{Command: "/bin/true"}
+	s.cfg.Mirrors = []config.MirrorTarget{mirror}
+
+	hs, err := s.host("home.example.com", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apex, err := s.host("example.com", config.ApexRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(configFile)
+	want := []string{"serve.home.example.com.lastip", "serve.home.example.com@backup.lastip", "serve.example.com.lastip", "serve.example.com@backup.lastip"}
+	var got []string
+	for _, tg := range append(hs.targets, apex.targets...) {
+		got = append(got, strings.TrimPrefix(tg.cacheFile, dir+string(filepath.Separator)))
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cache files %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/updater"
+)
+
+// Server exposes /healthz, /readyz and /status over HTTP
+type Server struct {
+	cfg     config.HealthConfig
+	updater *updater.Updater
+	srv     *http.Server
+}
+
+// NewServer constructor
+func NewServer(cfg config.HealthConfig, u *updater.Updater) *Server {
+	s := &Server{cfg: cfg, updater: u}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.HandleFunc("/status", s.handleStatus)
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// ListenAndServe listens on the configured address and serves until Shutdown
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves requests on l until Shutdown
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.srv.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server gracefully
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz reports ready when the last successful sync happened within
+// ready_intervals check intervals
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	st := s.updater.Status()
+	n := s.cfg.ReadyIntervals
+	if n == 0 {
+		n = config.DefaultReadyIntervals
+	}
+	window := time.Duration(n) * time.Duration(st.Interval) * time.Second
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if st.LastSuccess == nil || time.Since(*st.LastSuccess) > window {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not ready\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	enc.Encode(s.updater.Status())
+}
@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/log"
+)
+
+// Hook stages, also exported to commands as GODDNS_HOOK
+const (
+	PreUpdate  = "pre_update"
+	PostUpdate = "post_update"
+	OnFailure  = "on_failure"
+)
+
+// DefaultTimeout is used when 'hooks.timeout' is not set
+const DefaultTimeout = 30 * time.Second
+
+// Env describes the record update a hook is run for
+type Env struct {
+	Record string
+	OldIP  string
+	NewIP  string
+	Result string // "pending", "success" or "failure"
+	Error  string
+}
+
+// Runner runs the configured hook commands
+type Runner struct {
+	cfg     config.HooksConfig
+	timeout time.Duration
+}
+
+// NewRunner constructor. A nil cfg gives a Runner that does nothing.
+func NewRunner(cfg *config.HooksConfig) *Runner {
+	r := &Runner{timeout: DefaultTimeout}
+	if cfg != nil {
+		r.cfg = *cfg
+		if cfg.Timeout > 0 {
+			r.timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+	}
+	return r
+}
+
+// Run executes the commands of stage in order and stops at the first failure
+func (r *Runner) Run(stage string, env Env) error {
+	var commands []string
+	switch stage {
+	case PreUpdate:
+		commands = r.cfg.PreUpdate
+	case PostUpdate:
+		commands = r.cfg.PostUpdate
+	case OnFailure:
+		commands = r.cfg.OnFailure
+	}
+
+	for _, command := range commands {
+		if err := r.run(stage, command, env); err != nil {
+			return fmt.Errorf("%s hook '%s' failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) run(stage, command string, env Env) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"GODDNS_HOOK="+stage,
+		"GODDNS_RECORD="+env.Record,
+		"GODDNS_OLD_IP="+env.OldIP,
+		"GODDNS_NEW_IP="+env.NewIP,
+		"GODDNS_RESULT="+env.Result,
+		"GODDNS_ERROR="+env.Error,
+	)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// 超时后子进程仍持有输出管道时不无限等待
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		if line != "" {
+			log.Info("[%s] %s", stage, line)
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("%s hook '%s' finished in %s", stage, command, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	xnet "golang.org/x/net/proxy"
+)
+
+// DefaultTimeout is the request timeout used by all API clients
+const DefaultTimeout = 15 * time.Second
+
+// New returns an HTTP client that goes through proxyURL when it is not empty.
+// Supported schemes: http, https, socks5, socks5h.
+func New(proxyURL string, timeout time.Duration) (*http.Client, error) {
+	transport, err := NewTransport(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// NewTransport returns a transport honoring the 'proxy' config setting
+func NewTransport(proxyURL string) (*http.Transport, error) {
+	transport := &http.Transport{}
+	if proxyURL == "" {
+		return transport, nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("invalid proxy URL '%s': must include scheme (e.g. 'http://', 'https://', 'socks5://')", proxyURL)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(u)
+	case "socks5", "socks5h":
+		var auth *xnet.Auth
+		if u.User != nil {
+			pw, _ := u.User.Password()
+			auth = &xnet.Auth{User: u.User.Username(), Password: pw}
+		}
+		dialer, err := xnet.SOCKS5("tcp", u.Host, auth, xnet.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create socks5 dialer: %w", err)
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s' in proxy url", u.Scheme)
+	}
+	return transport, nil
+}
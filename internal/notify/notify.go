@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/log"
+)
+
+// EventKind is the reason a notification is sent
+type EventKind string
+
+const (
+	EventChange   EventKind = "change"   // a record was updated to a new address
+	EventFailure  EventKind = "failure"  // failure_threshold runs in a row failed
+	EventRecovery EventKind = "recovery" // a run succeeded after a failure alert
+)
+
+// DefaultFailureThreshold is used when 'notify.failure_threshold' is not set
+const DefaultFailureThreshold = 3
+
+// Event is passed to every notifier
+type Event struct {
+	Kind     EventKind `json:"event"`
+	Record   string    `json:"record,omitempty"`
+	OldIP    string    `json:"old_ip,omitempty"`
+	NewIP    string    `json:"new_ip,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Failures int       `json:"failures,omitempty"`
+	Host     string    `json:"host"`
+	Time     time.Time `json:"time"`
+}
+
+// Title returns a one-line summary of the event
+func (e Event) Title() string {
+	switch e.Kind {
+	case EventChange:
+		return fmt.Sprintf("goddns: %s updated", e.Record)
+	case EventFailure:
+		return "goddns: updates failing"
+	case EventRecovery:
+		return "goddns: updates recovered"
+	}
+	return "goddns: " + string(e.Kind)
+}
+
+// Message returns the human readable body of the event
+func (e Event) Message() string {
+	switch e.Kind {
+	case EventChange:
+		if e.OldIP == "" {
+			return fmt.Sprintf("%s set to %s (host %s)", e.Record, e.NewIP, e.Host)
+		}
+		return fmt.Sprintf("%s changed from %s to %s (host %s)", e.Record, e.OldIP, e.NewIP, e.Host)
+	case EventFailure:
+		return fmt.Sprintf("%d consecutive update runs failed on host %s. Last error: %s", e.Failures, e.Host, e.Error)
+	case EventRecovery:
+		return fmt.Sprintf("Updates on host %s succeed again after %d failed runs", e.Host, e.Failures)
+	}
+	return e.Title()
+}
+
+// Notifier delivers events to one destination. Implementations decide which
+// event kinds they forward.
+type Notifier interface {
+	Notify(ev Event) error
+	String() string
+}
+
+// flusher is implemented by notifiers that hold back events (rate limiting)
+// and need a chance to deliver them even when no new event arrives
+type flusher interface {
+	Flush() error
+}
+
+// dispatchState is persisted so one-shot runs (cron, systemd timer) count
+// consecutive failures across invocations
+type dispatchState struct {
+	Failures int  `json:"failures"`
+	Alerted  bool `json:"alerted"`
+}
+
+// Dispatcher turns update results into events and fans them out
+type Dispatcher struct {
+	notifiers []Notifier
+	threshold int
+	stateFile string
+	host      string
+
+	mu    sync.Mutex
+	state dispatchState
+	wg    sync.WaitGroup
+}
+
+// NewDispatcher builds the notifiers configured in cfg.Notify. stateFile keeps
+// the failure counter between runs.
+func NewDispatcher(cfg config.Config, stateFile string) (*Dispatcher, error) {
+	d := &Dispatcher{threshold: DefaultFailureThreshold, stateFile: stateFile}
+	d.host, _ = os.Hostname()
+	if cfg.Notify == nil {
+		return d, nil
+	}
+	if cfg.Notify.FailureThreshold > 0 {
+		d.threshold = cfg.Notify.FailureThreshold
+	}
+
+	for i, wh := range cfg.Notify.Webhooks {
+		n, err := NewWebhook(wh, cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("notify.webhooks[%d]: %w", i, err)
+		}
+		d.notifiers = append(d.notifiers, n)
+	}
+	if cfg.Notify.SMTP != nil {
+		smtpState := filepath.Join(filepath.Dir(stateFile), "notify.smtp.state")
+		d.notifiers = append(d.notifiers, NewSMTP(*cfg.Notify.SMTP, smtpState))
+	}
+
+	if data, err := os.ReadFile(stateFile); err == nil {
+		json.Unmarshal(data, &d.state)
+	}
+	return d, nil
+}
+
+// Changed reports that record now points at newIP instead of oldIP
+func (d *Dispatcher) Changed(record, oldIP, newIP string) {
+	d.send(Event{Kind: EventChange, Record: record, OldIP: oldIP, NewIP: newIP})
+}
+
+// Result records the outcome of one update run and sends failure/recovery
+// events when the failure streak crosses the threshold
+func (d *Dispatcher) Result(err error) {
+	if len(d.notifiers) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	var ev *Event
+	if err != nil {
+		d.state.Failures++
+		if d.state.Failures >= d.threshold && !d.state.Alerted {
+			d.state.Alerted = true
+			ev = &Event{Kind: EventFailure, Error: err.Error(), Failures: d.state.Failures}
+		}
+	} else {
+		if d.state.Alerted {
+			ev = &Event{Kind: EventRecovery, Failures: d.state.Failures}
+		}
+		d.state = dispatchState{}
+	}
+	d.saveState()
+	d.mu.Unlock()
+
+	if ev != nil {
+		d.send(*ev)
+		return
+	}
+	for _, n := range d.notifiers {
+		if f, ok := n.(flusher); ok {
+			d.wg.Add(1)
+			go func(n Notifier) {
+				defer d.wg.Done()
+				if err := f.Flush(); err != nil {
+					log.Error("Notification via %s failed: %v", n, err)
+				}
+			}(n)
+		}
+	}
+}
+
+func (d *Dispatcher) saveState() {
+	if d.stateFile == "" {
+		return
+	}
+	data, _ := json.Marshal(d.state)
+	if err := os.WriteFile(d.stateFile, data, 0644); err != nil {
+		log.Warning("Failed to write notify state %s: %v", d.stateFile, err)
+	}
+}
+
+// send delivers ev to all notifiers in the background
+func (d *Dispatcher) send(ev Event) {
+	ev.Host = d.host
+	ev.Time = time.Now()
+	for _, n := range d.notifiers {
+		d.wg.Add(1)
+		go func(n Notifier) {
+			defer d.wg.Done()
+			if err := n.Notify(ev); err != nil {
+				log.Error("Notification via %s failed: %v", n, err)
+			}
+		}(n)
+	}
+}
+
+// Wait blocks until pending notifications are delivered
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func wants(events []string, kind EventKind) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if EventKind(e) == kind {
+			return true
+		}
+	}
+	return false
+}
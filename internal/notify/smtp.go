@@ -0,0 +1,299 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/log"
+)
+
+// DefaultSMTPMinInterval is the minimum time between two emails when
+// 'notify.smtp.min_interval' is not set
+const DefaultSMTPMinInterval = 10 * time.Minute
+
+const (
+	smtpTimeout = 30 * time.Second
+	// 发送失败后的重试间隔从 smtpRetryDelay 开始加倍，最长 smtpMaxRetryDelay
+	smtpRetryDelay    = time.Minute
+	smtpMaxRetryDelay = time.Hour
+	// 最多保留的待发送事件，更早的事件只计数
+	smtpMaxPending = 50
+)
+
+// smtpState is persisted so rate limiting also holds across one-shot runs
+type smtpState struct {
+	LastSent    time.Time `json:"last_sent"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	Pending     []Event   `json:"pending,omitempty"`
+	Dropped     int       `json:"dropped,omitempty"` // 超出 smtpMaxPending 被丢弃的事件数
+}
+
+// nextSend returns the earliest time the next email may be sent: min_interval
+// after the last email, and after a failed attempt a doubling retry delay
+func (st smtpState) nextSend(minInterval time.Duration) time.Time {
+	next := st.LastSent.Add(minInterval)
+	if st.Failures > 0 {
+		delay := min(smtpRetryDelay<<min(st.Failures-1, 6), smtpMaxRetryDelay)
+		if t := st.LastAttempt.Add(delay); t.After(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// SMTP sends events by email. Events arriving within min_interval of the last
+// email are held back and sent together as one summary.
+type SMTP struct {
+	cfg         config.SMTPConfig
+	minInterval time.Duration
+	stateFile   string
+
+	mu       sync.Mutex
+	state    smtpState
+	timer    *time.Timer
+	sending  bool
+	inflight []Event // 正在发送的事件，发送失败时放回 Pending
+}
+
+// NewSMTP constructor. stateFile keeps the rate limit state between runs.
+func NewSMTP(cfg config.SMTPConfig, stateFile string) *SMTP {
+	s := &SMTP{cfg: cfg, stateFile: stateFile, minInterval: DefaultSMTPMinInterval}
+	if cfg.MinInterval > 0 {
+		s.minInterval = time.Duration(cfg.MinInterval) * time.Second
+	}
+	if data, err := os.ReadFile(stateFile); err == nil {
+		json.Unmarshal(data, &s.state)
+	}
+	return s
+}
+
+func (s *SMTP) String() string {
+	return "smtp " + s.cfg.Host
+}
+
+// Notify queues ev and sends the queue now if the rate limit allows it
+func (s *SMTP) Notify(ev Event) error {
+	if !wants(s.cfg.Events, ev.Kind) {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.state.Pending = append(s.state.Pending, ev)
+	s.trimPending()
+	return s.sendPending()
+}
+
+// Flush sends queued events once the rate limit window has passed
+func (s *SMTP) Flush() error {
+	s.mu.Lock()
+	if len(s.state.Pending) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	return s.sendPending()
+}
+
+func (s *SMTP) flush() {
+	s.mu.Lock()
+	s.timer = nil
+	if s.sending {
+		s.mu.Unlock()
+		return
+	}
+	// 配置重新加载后可能已有新的实例发送过，以状态文件为准
+	if data, err := os.ReadFile(s.stateFile); err == nil {
+		s.state = smtpState{}
+		json.Unmarshal(data, &s.state)
+	}
+	if err := s.sendPending(); err != nil {
+		log.Error("Notification via %s failed: %v", s, err)
+	}
+}
+
+// sendPending must be called with s.mu held and releases it. The queue is sent
+// outside the lock; on failure the events stay queued and a retry is scheduled.
+func (s *SMTP) sendPending() error {
+	if len(s.state.Pending) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.sending {
+		// 正在发送的一批结束后会为新事件安排下一次发送
+		s.saveState()
+		s.mu.Unlock()
+		return nil
+	}
+	if wait := time.Until(s.state.nextSend(s.minInterval)); wait > 0 {
+		log.Info("Email notification rate limited, %d event(s) queued for %s", len(s.state.Pending), wait.Round(time.Second))
+		s.schedule(wait)
+		s.saveState()
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.sending = true
+	s.inflight, s.state.Pending = s.state.Pending, nil
+	dropped := s.state.Dropped
+	s.state.Dropped = 0
+	events := s.inflight
+	s.mu.Unlock()
+
+	err := s.send(events, dropped)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sending = false
+	now := time.Now()
+	if err != nil {
+		s.state.Pending = append(s.inflight, s.state.Pending...)
+		s.state.Dropped += dropped
+		s.trimPending()
+		s.state.LastAttempt = now
+		s.state.Failures++
+	} else {
+		s.state.LastSent = now
+		s.state.LastAttempt = time.Time{}
+		s.state.Failures = 0
+	}
+	s.inflight = nil
+	if len(s.state.Pending) > 0 {
+		s.schedule(time.Until(s.state.nextSend(s.minInterval)))
+	}
+	s.saveState()
+	return err
+}
+
+// trimPending keeps the newest smtpMaxPending events. Must be called with s.mu held.
+func (s *SMTP) trimPending() {
+	if n := len(s.state.Pending) - smtpMaxPending; n > 0 {
+		s.state.Dropped += n
+		s.state.Pending = append([]Event(nil), s.state.Pending[n:]...)
+	}
+}
+
+// schedule arms the flush timer unless one is already pending. Must be called with s.mu held.
+func (s *SMTP) schedule(wait time.Duration) {
+	if s.timer == nil {
+		s.timer = time.AfterFunc(max(wait, 0), s.flush)
+	}
+}
+
+func (s *SMTP) saveState() {
+	st := s.state
+	if len(s.inflight) > 0 {
+		// 发送中的事件也要保存，进程在发送时退出不会丢失
+		st.Pending = append(append([]Event(nil), s.inflight...), st.Pending...)
+	}
+	data, _ := json.Marshal(st)
+	if err := os.WriteFile(s.stateFile, data, 0644); err != nil {
+		log.Warning("Failed to write notify state %s: %v", s.stateFile, err)
+	}
+}
+
+func (s *SMTP) send(events []Event, dropped int) error {
+	msg := s.buildMessage(events, dropped)
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.port()))
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.Security == "tls" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: smtpTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, smtpTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.cfg.Security == "" || s.cfg.Security == "starttls" {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP auth failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range s.cfg.To {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTP) port() int {
+	if s.cfg.Port != 0 {
+		return s.cfg.Port
+	}
+	switch s.cfg.Security {
+	case "tls":
+		return 465
+	case "none":
+		return 25
+	}
+	return 587
+}
+
+func (s *SMTP) buildMessage(events []Event, dropped int) []byte {
+	subject := events[0].Title()
+	if len(events)+dropped > 1 {
+		subject = fmt.Sprintf("goddns: %d events", len(events)+dropped)
+	}
+
+	var body strings.Builder
+	if dropped > 0 {
+		fmt.Fprintf(&body, "%d earlier event(s) omitted.\r\n", dropped)
+	}
+	for _, ev := range events {
+		fmt.Fprintf(&body, "[%s] %s\r\n", ev.Time.Format(time.RFC3339), ev.Message())
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(body.String())
+	return buf.Bytes()
+}
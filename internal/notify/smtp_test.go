@@ -0,0 +1,240 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goddns/internal/config"
+)
+
+// fakeSMTP is a minimal SMTP server that records the messages it accepts
+type fakeSMTP struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	conns    int
+	messages []string
+	reject   bool          // 对 DATA 返回 554
+	delay    time.Duration // 接受 DATA 前等待
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSMTP{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	f.mu.Lock()
+	f.conns++
+	reject, delay := f.reject, f.delay
+	f.mu.Unlock()
+
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "DATA"):
+			time.Sleep(delay)
+			if reject {
+				reply("554 rejected")
+				continue
+			}
+			reply("354 go ahead")
+			var msg strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				msg.WriteString(l)
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, msg.String())
+			f.mu.Unlock()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (f *fakeSMTP) set(reject bool, delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reject, f.delay = reject, delay
+}
+
+func (f *fakeSMTP) stats() (int, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns, append([]string(nil), f.messages...)
+}
+
+func newTestSMTP(t *testing.T, f *fakeSMTP) *SMTP {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(f.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	cfg := config.SMTPConfig{Host: host, Port: p, Security: "none", From: "goddns@example.com", To: []string{"admin@example.com"}}
+	return NewSMTP(cfg, filepath.Join(t.TempDir(), "notify.smtp.state"))
+}
+
+func TestSMTPRateLimit(t *testing.T) {
+	f := newFakeSMTP(t)
+	s := newTestSMTP(t, f)
+
+	if err := s.Notify(changeEvent("2001:db8::2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Notify(changeEvent("2001:db8::3")); err != nil {
+		t.Fatal(err)
+	}
+	_, msgs := f.stats()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "2001:db8::2") {
+		t.Fatalf("want one email for the first event, got %q", msgs)
+	}
+
+	// 状态文件保留排队的事件，新实例同样受限
+	s2 := NewSMTP(s.cfg, s.stateFile)
+	if len(s2.state.Pending) != 1 || s2.state.LastSent.IsZero() {
+		t.Fatalf("state not persisted: %+v", s2.state)
+	}
+	if err := s2.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, msgs := f.stats(); len(msgs) != 1 {
+		t.Fatalf("Flush sent within min_interval: %d emails", len(msgs))
+	}
+}
+
+func TestSMTPFailureBacksOff(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.set(true, 0)
+	s := newTestSMTP(t, f)
+
+	if err := s.Notify(changeEvent("2001:db8::2")); err == nil {
+		t.Fatal("want error for rejected email")
+	}
+	for i := 3; i < 10; i++ {
+		if err := s.Notify(changeEvent("2001:db8::" + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if conns, _ := f.stats(); conns != 1 {
+		t.Fatalf("want no retry before the backoff, got %d connections", conns)
+	}
+	if s.state.Failures != 1 || len(s.state.Pending) != 8 {
+		t.Fatalf("unexpected state after failure: failures=%d pending=%d", s.state.Failures, len(s.state.Pending))
+	}
+	if s.timer == nil {
+		t.Fatal("no retry scheduled")
+	}
+	s.timer.Stop()
+
+	// 退避结束后一次发送全部排队的事件
+	f.set(false, 0)
+	s.state.LastAttempt = time.Now().Add(-smtpRetryDelay)
+	s.timer = nil
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_, msgs := f.stats()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "8 events") {
+		t.Fatalf("want one summary of 8 events, got %q", msgs)
+	}
+	if s.state.Failures != 0 || len(s.state.Pending) != 0 {
+		t.Fatalf("state not reset after success: %+v", s.state)
+	}
+}
+
+func TestSMTPPendingCapped(t *testing.T) {
+	f := newFakeSMTP(t)
+	s := newTestSMTP(t, f)
+	s.state.LastSent = time.Now()
+
+	for i := 0; i < smtpMaxPending+10; i++ {
+		s.Notify(changeEvent("2001:db8::" + strconv.Itoa(i)))
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.state.Pending) != smtpMaxPending || s.state.Dropped != 10 {
+		t.Fatalf("want %d pending and 10 dropped, got %d and %d", smtpMaxPending, len(s.state.Pending), s.state.Dropped)
+	}
+
+	s.state.LastSent = time.Time{}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_, msgs := f.stats()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "10 earlier event(s) omitted") {
+		t.Fatalf("summary does not mention dropped events: %q", msgs)
+	}
+}
+
+func TestSMTPSendsOutsideLock(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.set(false, time.Second)
+	s := newTestSMTP(t, f)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Notify(changeEvent("2001:db8::2")) }()
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	if err := s.Notify(changeEvent("2001:db8::3")); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("Notify blocked for %s while another email was being sent", d)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.state.Pending) != 1 || s.timer == nil {
+		t.Fatalf("event queued during the send is not scheduled: pending=%d", len(s.state.Pending))
+	}
+	s.timer.Stop()
+}
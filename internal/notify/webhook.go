@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const (
+	defaultWebhookRetries = 3
+	webhookBaseDelay      = 1 * time.Second
+)
+
+// Webhook posts events to an HTTP endpoint
+type Webhook struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+	tmpl   *template.Template
+}
+
+// NewWebhook constructor. proxy is the global 'proxy' setting.
+func NewWebhook(cfg config.WebhookConfig, proxy string) (*Webhook, error) {
+	client, err := httpclient.New(proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	w := &Webhook{cfg: cfg, client: client}
+	if cfg.Body != "" {
+		tmpl, err := template.New("body").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+		w.tmpl = tmpl
+	}
+	return w, nil
+}
+
+func (w *Webhook) String() string {
+	u, err := url.Parse(w.cfg.URL)
+	if err != nil {
+		return "webhook"
+	}
+	return "webhook " + u.Host
+}
+
+// Notify sends ev, retrying on network errors, 429 and 5xx responses
+func (w *Webhook) Notify(ev Event) error {
+	if !wants(w.cfg.Events, ev.Kind) {
+		return nil
+	}
+
+	retries := defaultWebhookRetries
+	if w.cfg.Retries != nil {
+		retries = *w.cfg.Retries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(webhookBaseDelay * time.Duration(1<<(attempt-1)))
+		}
+
+		req, err := w.buildRequest(ev)
+		if err != nil {
+			return err
+		}
+		resp, err := w.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return lastErr
+		}
+	}
+	return fmt.Errorf("giving up after %d retries: %w", retries, lastErr)
+}
+
+func (w *Webhook) buildRequest(ev Event) (*http.Request, error) {
+	target := w.cfg.URL
+	contentType := "application/json"
+	var body []byte
+	var err error
+
+	switch w.cfg.Format {
+	case "slack":
+		body, err = json.Marshal(map[string]string{"text": "*" + ev.Title() + "*\n" + ev.Message()})
+	case "discord":
+		body, err = json.Marshal(map[string]string{"content": "**" + ev.Title() + "**\n" + ev.Message()})
+	case "gotify":
+		if !strings.HasSuffix(strings.TrimSuffix(target, "/"), "/message") {
+			target = strings.TrimSuffix(target, "/") + "/message"
+		}
+		priority := 5
+		if ev.Kind == EventFailure {
+			priority = 8
+		}
+		body, err = json.Marshal(map[string]interface{}{
+			"title":    ev.Title(),
+			"message":  ev.Message(),
+			"priority": priority,
+		})
+	case "ntfy":
+		contentType = "text/plain; charset=utf-8"
+		body = []byte(ev.Message())
+	default:
+		if w.tmpl != nil {
+			var buf bytes.Buffer
+			if err := w.tmpl.Execute(&buf, ev); err != nil {
+				return nil, fmt.Errorf("failed to render body template: %w", err)
+			}
+			body = buf.Bytes()
+		} else {
+			body, err = json.Marshal(ev)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "goddns")
+
+	switch w.cfg.Format {
+	case "ntfy":
+		req.Header.Set("Title", ev.Title())
+		switch ev.Kind {
+		case EventChange:
+			req.Header.Set("Tags", "globe_with_meridians")
+		case EventFailure:
+			req.Header.Set("Tags", "warning")
+			req.Header.Set("Priority", "high")
+		case EventRecovery:
+			req.Header.Set("Tags", "white_check_mark")
+		}
+		if w.cfg.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+w.cfg.Token)
+		}
+	case "gotify":
+		if w.cfg.Token != "" {
+			req.Header.Set("X-Gotify-Key", w.cfg.Token)
+		}
+	}
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goddns/internal/config"
+)
+
+// fakeEndpoint answers every request with status and records the bodies
+type fakeEndpoint struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	bodies []string
+}
+
+func newFakeEndpoint(t *testing.T, status int) *fakeEndpoint {
+	t.Helper()
+	f := &fakeEndpoint{status: status}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.bodies = append(f.bodies, string(body))
+		status := f.status
+		f.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeEndpoint) requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.bodies...)
+}
+
+func intPtr(v int) *int { return &v }
+
+func changeEvent(newIP string) Event {
+	return Event{Kind: EventChange, Host: "router", Record: "home.example.com", OldIP: "2001:db8::1", NewIP: newIP, Time: time.Now()}
+}
+
+func TestWebhookJSON(t *testing.T) {
+	f := newFakeEndpoint(t, http.StatusNoContent)
+	w, err := NewWebhook(config.WebhookConfig{URL: f.URL}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Notify(changeEvent("2001:db8::2")); err != nil {
+		t.Fatal(err)
+	}
+	reqs := f.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("want one request, got %d", len(reqs))
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(reqs[0]), &got); err != nil {
+		t.Fatalf("body is not JSON: %s", reqs[0])
+	}
+	if got["record"] != "home.example.com" || got["new_ip"] != "2001:db8::2" {
+		t.Errorf("unexpected body %s", reqs[0])
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		status   int
+		retries  *int
+		requests int
+	}{
+		// retries: 0 关闭重试，而不是使用默认值
+		{http.StatusServiceUnavailable, intPtr(0), 1},
+		{http.StatusServiceUnavailable, intPtr(1), 2},
+		{http.StatusTooManyRequests, intPtr(1), 2},
+		// 4xx 不重试
+		{http.StatusBadRequest, nil, 1},
+	}
+	for _, tt := range tests {
+		f := newFakeEndpoint(t, tt.status)
+		w, err := NewWebhook(config.WebhookConfig{URL: f.URL, Retries: tt.retries}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		err = w.Notify(changeEvent("2001:db8::2"))
+		if err == nil || !strings.Contains(err.Error(), "HTTP") {
+			t.Errorf("status %d: want an HTTP error, got %v", tt.status, err)
+		}
+		if n := len(f.requests()); n != tt.requests {
+			t.Errorf("status %d retries %v: %d requests, want %d", tt.status, tt.retries, n, tt.requests)
+		}
+		if tt.requests == 1 && time.Since(start) >= webhookBaseDelay {
+			t.Errorf("status %d retries %v: waited before giving up", tt.status, tt.retries)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+//go:build freebsd || openbsd
+
+package ifaddr
+
+// IN6_IFF_* from netinet6/in6_var.h. FreeBSD's IN6_IFF_PREFER_SOURCE (0x100)
+// only affects source address selection and has no counterpart.
+const (
+    in6IffAnycast    = 0x01
+    in6IffTentative  = 0x02
+    in6IffDuplicated = 0x04
+    in6IffDetached   = 0x08
+    in6IffDeprecated = 0x10
+    in6IffNoDAD      = 0x20
+    in6IffAutoconf   = 0x40
+    in6IffTemporary  = 0x80
+)
+
+var bsdAddrFlags = map[uint32]AddrFlags{
+    in6IffAnycast:    FlagAnycast,
+    in6IffTentative:  FlagTentative,
+    in6IffDuplicated: FlagDADFailed,
+    in6IffDetached:   FlagDetached,
+    in6IffDeprecated: FlagDeprecated,
+    in6IffNoDAD:      FlagNoDAD,
+    in6IffTemporary:  FlagTemporary,
+}
+
+func bsdFlags(raw uint32) AddrFlags {
+    var flags AddrFlags
+    for bit, flag := range bsdAddrFlags {
+        if raw&bit != 0 {
+            flags |= flag
+        }
+    }
+    // BSD 标记由路由通告生成的地址(autoconf)，其余地址与 Linux 的 permanent 相同
+    if raw&in6IffAutoconf == 0 {
+        flags |= FlagPermanent
+    }
+    return flags
+}
@@ -0,0 +1,29 @@
+//go:build freebsd || openbsd
+
+package ifaddr
+
+import "testing"
+
+func TestBSDFlags(t *testing.T) {
+    tests := []struct {
+        raw  uint32
+        want string
+    }{
+        // 路由通告生成的稳定地址
+        {in6IffAutoconf, ""},
+        {in6IffAutoconf | in6IffTemporary, "temporary"},
+        {in6IffAutoconf | in6IffDeprecated, "deprecated"},
+        // 手动配置的地址没有 autoconf
+        {0, "permanent"},
+        {in6IffTentative, "tentative permanent"},
+        {in6IffDuplicated | in6IffNoDAD, "dadfailed permanent nodad"},
+        {in6IffAnycast | in6IffDetached | in6IffAutoconf, "anycast detached"},
+        // 不支持的位被忽略
+        {0x100 | in6IffAutoconf, ""},
+    }
+    for _, tt := range tests {
+        if got := bsdFlags(tt.raw).String(); got != tt.want {
+            t.Errorf("bsdFlags(%#x) = %q, want %q", tt.raw, got, tt.want)
+        }
+    }
+}
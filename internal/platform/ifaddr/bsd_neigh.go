@@ -0,0 +1,10 @@
+//go:build freebsd || openbsd
+
+package ifaddr
+
+import "errors"
+
+// GetIPv6Neighbors is not implemented on the BSDs yet
+func GetIPv6Neighbors() ([]Neighbor, error) {
+    return nil, errors.New("reading the neighbor table is only supported on Linux")
+}
@@ -71,6 +71,14 @@ int get_ipv6info_freebsd(const char *ifname, char *addresses_buf, size_t max_len
             continue;
 
         struct in6_addrlifetime lt = ifr6.ifr_ifru.ifru_lifetime;
+
+        // 地址标志（IN6_IFF_*），读取失败时按无标志处理
+        unsigned int flags6 = 0;
+        memset(&ifr6.ifr_ifru, 0, sizeof(ifr6.ifr_ifru));
+        ifr6.ifr_addr = *sin6;
+        if (ioctl(s, SIOCGIFAFLAG_IN6, &ifr6) == 0)
+            flags6 = (unsigned int)ifr6.ifr_ifru.ifru_flags6;
+
         time_t now = time(NULL);
         
         // Convert to pltime/vltime format
@@ -83,8 +91,8 @@ int get_ipv6info_freebsd(const char *ifname, char *addresses_buf, size_t max_len
         }
 
         ptr += snprintf(ptr, remain,
-            "{\"addr\":\"%s\",\"pltime\":%u,\"vltime\":%u}",
-            addr_str, pltime, vltime);
+            "{\"addr\":\"%s\",\"pltime\":%u,\"vltime\":%u,\"flags\":%u}",
+            addr_str, pltime, vltime, flags6);
         remain = max_len - (ptr - addresses_buf);
         count++;
     }
@@ -117,6 +125,7 @@ type ioctlAddrInfo struct {
     Addr   string `json:"addr"`
     Pltime uint32 `json:"pltime"`  // Preferred lifetime in seconds
     Vltime uint32 `json:"vltime"`  // Valid lifetime in seconds
+    Flags  uint32 `json:"flags"`   // IN6_IFF_* flags
 }
 
 // GetAvailableIPv6 uses ioctl to get IPv6 info on FreeBSD
@@ -147,6 +156,7 @@ func GetAvailableIPv6(ifaceName string) ([]IPv6Info, error) {
                 IP:           ip,
                 PreferredLft: time.Duration(a.Pltime) * time.Second,
                 ValidLft:     time.Duration(a.Vltime) * time.Second,
+                Flags:        bsdFlags(a.Flags),
             }
             populateInfo(&info)
             infos = append(infos, info)
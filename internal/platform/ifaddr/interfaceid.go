@@ -0,0 +1,69 @@
+package ifaddr
+
+import (
+    "errors"
+    "fmt"
+    "net"
+
+    "goddns/internal/config"
+)
+
+// DefaultHostPrefixLength is used when a host does not set 'prefix_length'
+const DefaultHostPrefixLength = 64
+
+// EUI64 returns the modified EUI-64 interface identifier of a 48-bit MAC
+// address (RFC 4291 appendix A) as the low 64 bits of an IPv6 address
+func EUI64(mac net.HardwareAddr) (net.IP, error) {
+    if len(mac) != 6 {
+        return nil, fmt.Errorf("EUI-64 needs a 48-bit MAC address, got %s", mac)
+    }
+    iid := make(net.IP, net.IPv6len)
+    iid[8] = mac[0] ^ 0x02 // universal/local 位取反
+    iid[9] = mac[1]
+    iid[10] = mac[2]
+    iid[11] = 0xff
+    iid[12] = 0xfe
+    iid[13] = mac[3]
+    iid[14] = mac[4]
+    iid[15] = mac[5]
+    return iid, nil
+}
+
+// ComposeAddress keeps the first prefixLen bits of prefix and takes the
+// remaining bits from iid
+func ComposeAddress(prefix net.IP, prefixLen int, iid net.IP) (net.IP, error) {
+    p, s := prefix.To16(), iid.To16()
+    if p == nil || s == nil || prefix.To4() != nil {
+        return nil, errors.New("prefix and interface identifier must be IPv6 addresses")
+    }
+    if prefixLen < 1 || prefixLen > 127 {
+        return nil, fmt.Errorf("invalid prefix length %d", prefixLen)
+    }
+    mask := net.CIDRMask(prefixLen, 128)
+    out := make(net.IP, net.IPv6len)
+    for i := range out {
+        out[i] = p[i]&mask[i] | s[i]&^mask[i]
+    }
+    return out, nil
+}
+
+// HostAddress returns the address of host inside the prefix of ip
+func HostAddress(ip net.IP, host config.HostConfig) (net.IP, error) {
+    var iid net.IP
+    if host.MAC != "" {
+        mac, err := net.ParseMAC(host.MAC)
+        if err != nil {
+            return nil, err
+        }
+        if iid, err = EUI64(mac); err != nil {
+            return nil, err
+        }
+    } else if iid = net.ParseIP(host.Suffix); iid == nil {
+        return nil, fmt.Errorf("invalid interface identifier '%s'", host.Suffix)
+    }
+    prefixLen := host.PrefixLength
+    if prefixLen == 0 {
+        prefixLen = DefaultHostPrefixLength
+    }
+    return ComposeAddress(ip, prefixLen, iid)
+}
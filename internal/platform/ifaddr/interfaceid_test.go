@@ -0,0 +1,63 @@
+package ifaddr
+
+import (
+    "net"
+    "testing"
+
+    "goddns/internal/config"
+)
+
+func TestEUI64(t *testing.T) {
+    tests := []struct {
+        mac  string
+        want string
+    }{
+        {"00:00:5e:00:53:01", "::200:5eff:fe00:5301"}, // RFC 7042 示例，U/L 位置 1
+        {"52:54:00:12:34:56", "::5054:ff:fe12:3456"},  // 本地管理地址，U/L 位清零
+    }
+    for _, tt := range tests {
+        mac, _ := net.ParseMAC(tt.mac)
+        iid, err := EUI64(mac)
+        if err != nil || iid.String() != tt.want {
+            t.Errorf("EUI64(%s) = %s, %v; want %s", tt.mac, iid, err, tt.want)
+        }
+    }
+
+    mac, _ := net.ParseMAC("00:00:5e:ff:fe:00:53:01")
+    if _, err := EUI64(mac); err == nil {
+        t.Error("EUI64 accepted a 64-bit MAC address")
+    }
+}
+
+func TestHostAddress(t *testing.T) {
+    detected := net.ParseIP("2001:db8:1234:5678:aaaa:bbbb:cccc:dddd")
+    tests := []struct {
+        host config.HostConfig
+        want string
+    }{
+        {config.HostConfig{Suffix: "::10"}, "2001:db8:1234:5678::10"},
+        {config.HostConfig{MAC: "52:54:00:12:34:56"}, "2001:db8:1234:5678:5054:ff:fe12:3456"},
+        // 更短的前缀长度保留更少的检测地址位
+        {config.HostConfig{Suffix: "::42:0:0:0:10", PrefixLength: 48}, "2001:db8:1234:42::10"},
+        {config.HostConfig{Suffix: "::ff:0:0:0:10", PrefixLength: 56}, "2001:db8:1234:56ff::10"},
+    }
+    for _, tt := range tests {
+        ip, err := HostAddress(detected, tt.host)
+        if err != nil || ip.String() != tt.want {
+            t.Errorf("HostAddress(%+v) = %s, %v; want %s", tt.host, ip, err, tt.want)
+        }
+    }
+
+    for _, host := range []config.HostConfig{
+        {Suffix: "nas"},
+        {MAC: "52:54:00:12:34"},
+        {Suffix: "::10", PrefixLength: 128},
+    } {
+        if ip, err := HostAddress(detected, host); err == nil {
+            t.Errorf("HostAddress(%+v) = %s, want an error", host, ip)
+        }
+    }
+    if _, err := ComposeAddress(net.ParseIP("192.0.2.1"), 64, net.ParseIP("::10")); err == nil {
+        t.Error("ComposeAddress accepted an IPv4 prefix")
+    }
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package ifaddr
+
+import (
+    "fmt"
+    "net"
+    "time"
+
+    stdnetlink "github.com/vishvananda/netlink"
+)
+
+// 内核以 USER_HZ(100) 为单位报告邻居表时间
+const clockTicksPerSecond = 100
+
+var neighborStates = map[int]string{
+    stdnetlink.NUD_INCOMPLETE: "incomplete",
+    stdnetlink.NUD_REACHABLE:  "reachable",
+    stdnetlink.NUD_STALE:      "stale",
+    stdnetlink.NUD_DELAY:      "delay",
+    stdnetlink.NUD_PROBE:      "probe",
+    stdnetlink.NUD_FAILED:     "failed",
+    stdnetlink.NUD_NOARP:      "noarp",
+    stdnetlink.NUD_PERMANENT:  "permanent",
+}
+
+// GetIPv6Neighbors returns the IPv6 neighbor table of all interfaces using netlink
+func GetIPv6Neighbors() ([]Neighbor, error) {
+    list, err := stdnetlink.NeighList(0, stdnetlink.FAMILY_V6)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read neighbor table: %w", err)
+    }
+
+    names := map[int]string{}
+    var neighbors []Neighbor
+    for _, n := range list {
+        if n.IP == nil || len(n.HardwareAddr) == 0 {
+            continue
+        }
+        name, ok := names[n.LinkIndex]
+        if !ok {
+            if iface, err := net.InterfaceByIndex(n.LinkIndex); err == nil {
+                name = iface.Name
+            }
+            names[n.LinkIndex] = name
+        }
+        state, ok := neighborStates[n.State]
+        if !ok {
+            state = fmt.Sprintf("0x%x", n.State)
+        }
+        neighbors = append(neighbors, Neighbor{
+            IP:        n.IP,
+            MAC:       n.HardwareAddr,
+            Interface: name,
+            State:     state,
+            Confirmed: time.Duration(n.Confirmed) * time.Second / clockTicksPerSecond,
+        })
+    }
+    return neighbors, nil
+}
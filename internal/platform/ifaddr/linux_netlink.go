@@ -15,6 +15,42 @@ type netlinkAddrInfo struct {
     Addr   string `json:"addr"`
     Pltime uint32 `json:"pltime"`
     Vltime uint32 `json:"vltime"`
+    Flags  uint32 `json:"flags"`
+}
+
+// IFA_F_* from linux/if_addr.h
+const (
+    ifaFTemporary      = 0x01
+    ifaFNoDAD          = 0x02
+    ifaFOptimistic     = 0x04
+    ifaFDADFailed      = 0x08
+    ifaFDeprecated     = 0x20
+    ifaFTentative      = 0x40
+    ifaFPermanent      = 0x80
+    ifaFManageTempAddr = 0x100
+    ifaFNoPrefixRoute  = 0x200
+)
+
+var linuxAddrFlags = map[uint32]AddrFlags{
+    ifaFTemporary:      FlagTemporary,
+    ifaFNoDAD:          FlagNoDAD,
+    ifaFOptimistic:     FlagOptimistic,
+    ifaFDADFailed:      FlagDADFailed,
+    ifaFDeprecated:     FlagDeprecated,
+    ifaFTentative:      FlagTentative,
+    ifaFPermanent:      FlagPermanent,
+    ifaFManageTempAddr: FlagManageTmpAddr,
+    ifaFNoPrefixRoute:  FlagNoPrefixRoute,
+}
+
+func linuxFlags(raw uint32) AddrFlags {
+    var flags AddrFlags
+    for bit, flag := range linuxAddrFlags {
+        if raw&bit != 0 {
+            flags |= flag
+        }
+    }
+    return flags
 }
 
 // GetAvailableIPv6 returns IPv6 addresses from an interface using netlink
@@ -42,6 +78,7 @@ func GetAvailableIPv6(interfaceName string) ([]IPv6Info, error) {
             Addr:   addr.IP.String(),
             Pltime: uint32(addr.PreferedLft),
             Vltime: uint32(addr.ValidLft),
+            Flags:  uint32(addr.Flags),
         }
         addrInfos = append(addrInfos, addrInfo)
     }
@@ -68,6 +105,7 @@ func GetAvailableIPv6(interfaceName string) ([]IPv6Info, error) {
             IP:           ip,
             PreferredLft: time.Duration(addrInfo.Pltime) * time.Second,
             ValidLft:     time.Duration(addrInfo.Vltime) * time.Second,
+            Flags:        linuxFlags(addrInfo.Flags),
         }
         populateInfo(&info)
         infos = append(infos, info)
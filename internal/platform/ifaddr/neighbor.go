@@ -0,0 +1,62 @@
+package ifaddr
+
+import (
+    "bytes"
+    "fmt"
+    "net"
+    "sort"
+    "time"
+)
+
+// Neighbor is one IPv6 entry of the neighbor (NDP) table
+type Neighbor struct {
+    IP        net.IP
+    MAC       net.HardwareAddr
+    Interface string
+    State     string        // reachable, stale, delay, probe, permanent, ...
+    Confirmed time.Duration // time since the neighbor was last confirmed reachable
+}
+
+// usable reports whether the entry is a global address the host still answered for
+func (n Neighbor) usable() bool {
+    if n.State == "failed" || n.State == "incomplete" {
+        return false
+    }
+    ip := n.IP
+    return ip.To4() == nil && ip.IsGlobalUnicast() && !(ip[0] == 0xfc || ip[0] == 0xfd)
+}
+
+// SelectNeighborAddress picks the address of the host with mac. Temporary
+// (privacy) addresses rotate, so the address whose interface identifier is
+// the EUI-64 of mac is preferred, then the one seen for the longest time
+// according to firstSeen, then the most recently confirmed one.
+func SelectNeighborAddress(neighbors []Neighbor, mac net.HardwareAddr, firstSeen map[string]time.Time) (net.IP, error) {
+    var candidates []Neighbor
+    for _, n := range neighbors {
+        if bytes.Equal(n.MAC, mac) && n.usable() {
+            candidates = append(candidates, n)
+        }
+    }
+    if len(candidates) == 0 {
+        return nil, fmt.Errorf("no global address of %s in the neighbor table", mac)
+    }
+
+    eui, _ := EUI64(mac)
+    isEUI := func(ip net.IP) bool { return eui != nil && bytes.Equal(ip[8:], eui[8:]) }
+    sort.SliceStable(candidates, func(i, j int) bool {
+        a, b := candidates[i], candidates[j]
+        if isEUI(a.IP) != isEUI(b.IP) {
+            return isEUI(a.IP)
+        }
+        fa, oka := firstSeen[a.IP.String()]
+        fb, okb := firstSeen[b.IP.String()]
+        if oka && okb && !fa.Equal(fb) {
+            return fa.Before(fb)
+        }
+        if oka != okb {
+            return oka
+        }
+        return a.Confirmed < b.Confirmed
+    })
+    return candidates[0].IP, nil
+}
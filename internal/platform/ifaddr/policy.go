@@ -0,0 +1,152 @@
+package ifaddr
+
+import (
+    "bytes"
+    "fmt"
+    "net"
+    "sort"
+    "time"
+
+    "goddns/internal/config"
+)
+
+// DefaultSelectionOrder is used when 'get_ip.policy.order' is empty. Without
+// a suffix or prefer_state only the preferred lifetime decides.
+var DefaultSelectionOrder = []string{"suffix", "state", "lifetime"}
+
+// Evaluation is how the selection policy judged one address
+type Evaluation struct {
+    Info        IPv6Info
+    Rejected    string // why the address cannot be selected, empty for candidates
+    SuffixMatch bool   // interface identifier matches policy.suffix
+    StateMatch  bool   // AddressState matches policy.prefer_state
+    Kept        bool   // ranked first because it is the current address (policy.sticky)
+    Rank        int    // 1 for the selected address, 0 when rejected
+}
+
+// SelectionOrder returns the tie-breakers applied by cfg, most important first
+func SelectionOrder(cfg config.Config) []string {
+    if p := cfg.GetIP.Policy; p != nil && len(p.Order) > 0 {
+        return p.Order
+    }
+    return DefaultSelectionOrder
+}
+
+// EvaluateIPv6 filters infos with the selection policy of cfg and ranks the
+// remaining addresses. With policy.sticky, current stays first while it is
+// ranked at all and its preferred lifetime is above policy.sticky_margin.
+// The result is in the order of infos.
+func EvaluateIPv6(cfg config.Config, infos []IPv6Info, current string) []Evaluation {
+    var policy config.SelectionPolicy
+    if cfg.GetIP.Policy != nil {
+        policy = *cfg.GetIP.Policy
+    }
+    allow, deny := parseCIDRs(policy.Allow), parseCIDRs(policy.Deny)
+    minLifetime := time.Duration(policy.MinLifetime) * time.Second
+
+    evals := make([]Evaluation, len(infos))
+    var ranked []int
+    for i, info := range infos {
+        ev := Evaluation{Info: info, Rejected: rejectReason(info, cfg.GetIP.AllowTemporary)}
+        ev.Info.IsCandidate = ev.Rejected == ""
+        switch {
+        case ev.Rejected != "":
+            // 未通过基本过滤，原因已由 rejectReason 给出
+        case len(allow) > 0 && findCIDR(allow, info.IP) == nil:
+            ev.Rejected = "outside policy.allow"
+        case findCIDR(deny, info.IP) != nil:
+            ev.Rejected = fmt.Sprintf("in policy.deny %s", findCIDR(deny, info.IP))
+        case info.PreferredLft < minLifetime:
+            ev.Rejected = fmt.Sprintf("preferred lifetime below policy.min_lifetime %ds", policy.MinLifetime)
+        default:
+            ev.SuffixMatch = matchSuffix(info.IP, policy.Suffix)
+            ev.StateMatch = matchState(info.AddressState, policy.PreferState)
+            ranked = append(ranked, i)
+        }
+        evals[i] = ev
+    }
+
+    order := SelectionOrder(cfg)
+    sort.SliceStable(ranked, func(a, b int) bool {
+        return better(evals[ranked[a]], evals[ranked[b]], order)
+    })
+    if policy.Sticky {
+        margin := time.Duration(policy.StickyMargin) * time.Second
+        for r, i := range ranked {
+            ev := &evals[i]
+            if ev.Info.IP.String() == current && ev.Info.PreferredLft >= margin {
+                ev.Kept = r > 0
+                ranked = append(append([]int{i}, ranked[:r]...), ranked[r+1:]...)
+                break
+            }
+        }
+    }
+    for r, i := range ranked {
+        evals[i].Rank = r + 1
+    }
+    return evals
+}
+
+// better reports whether a wins over b with the given tie-breakers
+func better(a, b Evaluation, order []string) bool {
+    for _, c := range order {
+        switch c {
+        case "suffix":
+            if a.SuffixMatch != b.SuffixMatch {
+                return a.SuffixMatch
+            }
+        case "state":
+            if a.StateMatch != b.StateMatch {
+                return a.StateMatch
+            }
+        case "lifetime":
+            if a.Info.PreferredLft != b.Info.PreferredLft {
+                return a.Info.PreferredLft > b.Info.PreferredLft
+            }
+        }
+    }
+    return false
+}
+
+// matchSuffix compares the interface identifier (low 64 bits) of ip with
+// suffix. "eui64" matches any identifier derived from a MAC address.
+func matchSuffix(ip net.IP, suffix string) bool {
+    ip = ip.To16()
+    switch suffix {
+    case "":
+        return false
+    case "eui64":
+        return ip[11] == 0xff && ip[12] == 0xfe
+    }
+    s := net.ParseIP(suffix)
+    return s != nil && bytes.Equal(ip[8:], s.To16()[8:])
+}
+
+func matchState(state, prefer string) bool {
+    switch prefer {
+    case "static":
+        return state == "Preferred/Static"
+    case "dynamic":
+        return state == "Preferred/Dynamic"
+    }
+    return false
+}
+
+func parseCIDRs(cidrs []string) []*net.IPNet {
+    var out []*net.IPNet
+    for _, c := range cidrs {
+        if _, n, err := net.ParseCIDR(c); err == nil {
+            out = append(out, n)
+        }
+    }
+    return out
+}
+
+func findCIDR(nets []*net.IPNet, ip net.IP) *net.IPNet {
+    for _, n := range nets {
+        if n.Contains(ip) {
+            return n
+        }
+    }
+    return nil
+}
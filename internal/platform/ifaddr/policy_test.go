@@ -0,0 +1,154 @@
+package ifaddr
+
+import (
+    "net"
+    "strings"
+    "testing"
+    "time"
+
+    "goddns/internal/config"
+)
+
+func addr(ip string, preferred, valid time.Duration, flags AddrFlags) IPv6Info {
+    info := IPv6Info{IP: net.ParseIP(ip), PreferredLft: preferred, ValidLft: valid, Flags: flags}
+    populateInfo(&info)
+    return info
+}
+
+func policyConfig(p *config.SelectionPolicy) config.Config {
+    var cfg config.Config
+    cfg.GetIP.Policy = p
+    return cfg
+}
+
+// evaluate ranks infos without a current address
+func evaluate(cfg config.Config, infos []IPv6Info) []Evaluation {
+    return EvaluateIPv6(cfg, infos, "")
+}
+
+// winner returns the address ranked first, "" when none is
+func winner(evals []Evaluation) string {
+    for _, ev := range evals {
+        if ev.Rank == 1 {
+            return ev.Info.IP.String()
+        }
+    }
+    return ""
+}
+
+var testAddrs = []IPv6Info{
+    addr("2001:db8:1::1", time.Hour, time.Hour, 0),                      // 静态
+    addr("2001:db8:1:0:5054:ff:fe12:3456", 4*time.Hour, 8*time.Hour, 0), // SLAAC EUI-64
+    addr("2001:db8:2::abcd", 2*time.Hour, 8*time.Hour, 0),               // 另一前缀
+    addr("fd00::1", 24*time.Hour, 24*time.Hour, 0),                      // ULA
+    addr("2001:db8:1::dead", 0, time.Hour, FlagDeprecated),              // 已弃用
+    addr("2001:db8:1::beef", 12*time.Hour, 24*time.Hour, FlagTemporary), // 临时地址
+}
+
+func TestEvaluateIPv6Default(t *testing.T) {
+    evals := evaluate(config.Config{}, testAddrs)
+    if got := winner(evals); got != "2001:db8:1:0:5054:ff:fe12:3456" {
+        t.Errorf("winner %s, want the longest preferred lifetime", got)
+    }
+    wantRank := []int{3, 1, 2, 0, 0, 0}
+    for i, ev := range evals {
+        if ev.Rank != wantRank[i] || (ev.Rank == 0) == (ev.Rejected == "") {
+            t.Errorf("%s: rank %d (%q), want %d", ev.Info.IP, ev.Rank, ev.Rejected, wantRank[i])
+        }
+    }
+
+    cfg := config.Config{}
+    cfg.GetIP.AllowTemporary = true
+    if got := winner(evaluate(cfg, testAddrs)); got != "2001:db8:1::beef" {
+        t.Errorf("allow_temporary: winner %s", got)
+    }
+}
+
+func TestEvaluateIPv6Policy(t *testing.T) {
+    tests := []struct {
+        name     string
+        policy   config.SelectionPolicy
+        want     string
+        rejected map[string]string // 地址 → 拒绝原因片段
+    }{
+        {"suffix", config.SelectionPolicy{Suffix: "::1"}, "2001:db8:1::1", nil},
+        {"eui64", config.SelectionPolicy{Suffix: "eui64"}, "2001:db8:1:0:5054:ff:fe12:3456", nil},
+        {"static", config.SelectionPolicy{PreferState: "static"}, "2001:db8:1::1", nil},
+        {"dynamic", config.SelectionPolicy{PreferState: "dynamic"}, "2001:db8:1:0:5054:ff:fe12:3456", nil},
+        {"allow", config.SelectionPolicy{Allow: []string{"2001:db8:2::/48"}}, "2001:db8:2::abcd",
+            map[string]string{"2001:db8:1::1": "outside policy.allow"}},
+        {"deny", config.SelectionPolicy{Deny: []string{"2001:db8:1::/48"}}, "2001:db8:2::abcd",
+            map[string]string{"2001:db8:1:0:5054:ff:fe12:3456": "in policy.deny 2001:db8:1::/48"}},
+        {"min lifetime", config.SelectionPolicy{MinLifetime: 5 * 3600}, "",
+            map[string]string{"2001:db8:2::abcd": "below policy.min_lifetime 18000s"}},
+        // 默认 suffix 优先于 state，order 可调整比较顺序
+        {"suffix before state", config.SelectionPolicy{Suffix: "::abcd", PreferState: "static"}, "2001:db8:2::abcd", nil},
+        {"state before suffix", config.SelectionPolicy{Suffix: "::abcd", PreferState: "static", Order: []string{"state", "suffix"}}, "2001:db8:1::1", nil},
+        {"lifetime first", config.SelectionPolicy{Suffix: "::1", Order: []string{"lifetime", "suffix"}}, "2001:db8:1:0:5054:ff:fe12:3456", nil},
+    }
+    for _, tt := range tests {
+        evals := evaluate(policyConfig(&tt.policy), testAddrs)
+        if got := winner(evals); got != tt.want {
+            t.Errorf("%s: winner %q, want %q", tt.name, got, tt.want)
+        }
+        for _, ev := range evals {
+            if want, ok := tt.rejected[ev.Info.IP.String()]; ok && !strings.Contains(ev.Rejected, want) {
+                t.Errorf("%s: %s rejected with %q, want %q", tt.name, ev.Info.IP, ev.Rejected, want)
+            }
+        }
+    }
+}
+
+func TestSelectBestIPv6(t *testing.T) {
+    ip, err := SelectBestIPv6(policyConfig(&config.SelectionPolicy{Suffix: "::1"}), testAddrs, "")
+    if err != nil || ip != "2001:db8:1::1" {
+        t.Errorf("SelectBestIPv6 = %s, %v", ip, err)
+    }
+
+    _, err = SelectBestIPv6(policyConfig(&config.SelectionPolicy{Allow: []string{"2001:db8:ff::/48"}}), testAddrs, "")
+    if err == nil || !strings.Contains(err.Error(), "none of the 3 DDNS candidates passes 'get_ip.policy'") {
+        t.Errorf("policy rejecting all: %v", err)
+    }
+
+    _, err = SelectBestIPv6(config.Config{}, testAddrs[3:], "")
+    if err == nil || !strings.Contains(err.Error(), "no suitable DDNS Candidate") {
+        t.Errorf("no candidates: %v", err)
+    }
+}
+
+func TestEvaluateIPv6Sticky(t *testing.T) {
+    const current = "2001:db8:2::abcd" // 剩余 2 小时，默认不会被选中
+    tests := []struct {
+        name    string
+        policy  config.SelectionPolicy
+        current string
+        want    string
+        kept    bool
+    }{
+        {"off", config.SelectionPolicy{}, current, "2001:db8:1:0:5054:ff:fe12:3456", false},
+        {"kept", config.SelectionPolicy{Sticky: true}, current, current, true},
+        {"within margin", config.SelectionPolicy{Sticky: true, StickyMargin: 3600}, current, current, true},
+        {"below margin", config.SelectionPolicy{Sticky: true, StickyMargin: 3 * 3600}, current, "2001:db8:1:0:5054:ff:fe12:3456", false},
+        {"already best", config.SelectionPolicy{Sticky: true}, "2001:db8:1:0:5054:ff:fe12:3456", "2001:db8:1:0:5054:ff:fe12:3456", false},
+        // 当前地址不再是候选或被策略排除时切换
+        {"deprecated", config.SelectionPolicy{Sticky: true}, "2001:db8:1::dead", "2001:db8:1:0:5054:ff:fe12:3456", false},
+        {"denied", config.SelectionPolicy{Sticky: true, Deny: []string{"2001:db8:2::/48"}}, current, "2001:db8:1:0:5054:ff:fe12:3456", false},
+        {"gone", config.SelectionPolicy{Sticky: true}, "2001:db8:9::1", "2001:db8:1:0:5054:ff:fe12:3456", false},
+    }
+    for _, tt := range tests {
+        evals := EvaluateIPv6(policyConfig(&tt.policy), testAddrs, tt.current)
+        if got := winner(evals); got != tt.want {
+            t.Errorf("%s: winner %s, want %s", tt.name, got, tt.want)
+        }
+        for _, ev := range evals {
+            if ev.Kept != (tt.kept && ev.Info.IP.String() == tt.current) {
+                t.Errorf("%s: %s kept=%v", tt.name, ev.Info.IP, ev.Kept)
+            }
+        }
+    }
+
+    ip, err := SelectBestIPv6(policyConfig(&config.SelectionPolicy{Sticky: true}), testAddrs, current)
+    if err != nil || ip != current {
+        t.Errorf("SelectBestIPv6 = %s, %v", ip, err)
+    }
+}
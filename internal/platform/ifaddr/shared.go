@@ -2,9 +2,64 @@ package ifaddr
 
 import (
     "net"
+    "strings"
     "time"
 )
 
+// AddrFlags are the kernel flags of an address, normalized across platforms
+type AddrFlags uint32
+
+const (
+    FlagTemporary     AddrFlags = 1 << iota // RFC 4941 temporary (privacy) address
+    FlagManageTmpAddr                       // Linux mngtmpaddr: temporary addresses are derived from it
+    FlagTentative                           // duplicate address detection still running
+    FlagDADFailed                           // duplicate address detection failed
+    FlagOptimistic                          // RFC 4429 optimistic DAD
+    FlagNoPrefixRoute                       // no prefix route was added for the address
+    FlagPermanent                           // configured statically, not learned from router advertisements
+    FlagDeprecated                          // preferred lifetime expired
+    FlagNoDAD                               // duplicate address detection is disabled
+    FlagAnycast                             // BSD anycast address, shared with other hosts
+    FlagDetached                            // BSD: no router advertises the prefix on this link any more
+)
+
+var flagNames = []struct {
+    flag AddrFlags
+    name string
+}{
+    {FlagTemporary, "temporary"},
+    {FlagManageTmpAddr, "mngtmpaddr"},
+    {FlagTentative, "tentative"},
+    {FlagDADFailed, "dadfailed"},
+    {FlagOptimistic, "optimistic"},
+    {FlagNoPrefixRoute, "noprefixroute"},
+    {FlagPermanent, "permanent"},
+    {FlagDeprecated, "deprecated"},
+    {FlagNoDAD, "nodad"},
+    {FlagAnycast, "anycast"},
+    {FlagDetached, "detached"},
+}
+
+// Has reports whether all of flag are set
+func (f AddrFlags) Has(flag AddrFlags) bool {
+    return f&flag == flag
+}
+
+// Names returns the names of the set flags, as printed by `ip -6 addr`
+func (f AddrFlags) Names() []string {
+    var names []string
+    for _, fn := range flagNames {
+        if f.Has(fn.flag) {
+            names = append(names, fn.name)
+        }
+    }
+    return names
+}
+
+func (f AddrFlags) String() string {
+    return strings.Join(f.Names(), " ")
+}
+
 // IPv6Info contains information about an IPv6 address
 // 统一结构体，供各平台实现复用
 // 只在 shared.go 定义，其他文件引用
@@ -18,6 +73,7 @@ type IPv6Info struct {
     IsDeprecated   bool
     IsUniqueLocal  bool
     IsCandidate    bool // Whether it is a DDNS candidate
+    Flags          AddrFlags
 }
 
 // populateInfo 填充 IPv6Info 的附加属性
@@ -33,7 +89,7 @@ func populateInfo(info *IPv6Info) {
         info.Scope = "Global Unicast"
     }
 
-    info.IsDeprecated = info.PreferredLft.Seconds() <= 0 && info.ValidLft.Seconds() > 0
+    info.IsDeprecated = (info.PreferredLft.Seconds() <= 0 || info.Flags.Has(FlagDeprecated)) && info.ValidLft.Seconds() > 0
 
     if info.ValidLft.Seconds() == 0 {
         info.AddressState = "Expired"
@@ -45,7 +101,9 @@ func populateInfo(info *IPv6Info) {
         info.AddressState = "Preferred/Static"
     }
 
-    info.IsCandidate = info.Scope == "Global Unicast" && !info.IsDeprecated && !info.IsUniqueLocal
+    // 临时地址是否可用取决于 get_ip.allow_temporary，由 IsCandidate 判断
+    info.IsCandidate = info.Scope == "Global Unicast" && !info.IsDeprecated && !info.IsUniqueLocal &&
+        info.Flags&(FlagTentative|FlagDADFailed|FlagAnycast|FlagDetached) == 0
 }
 
 // IsPrivateOrLocalIP returns true for non-global addresses
@@ -7,99 +7,87 @@ import (
     "io"
     "net"
     "net/http"
-    "net/url"
     "strings"
     "time"
 
     "goddns/internal/config"
+    "goddns/internal/httpclient"
     "goddns/internal/log"
-
-    xnet "golang.org/x/net/proxy"
 )
 
-// SelectBestIPv6 selects the best IPv6 based on PreferredLft
-func SelectBestIPv6(cfg config.Config, infos []IPv6Info) (string, error) {
-    candidates := filterValidAddresses(infos)
-
-    if len(candidates) == 0 {
-        return "", errors.New("no suitable DDNS Candidate (Global Unicast, not deprecated) found")
-    }
-
-    var bestCandidate IPv6Info
-    maxPreferredLft := time.Duration(0)
-    for _, info := range candidates {
-        if info.PreferredLft > maxPreferredLft {
-            maxPreferredLft = info.PreferredLft
-            bestCandidate = info
+// SelectBestIPv6 selects the address ranked first by EvaluateIPv6. Without
+// a policy this is the candidate with the largest PreferredLft. current is
+// the address kept by policy.sticky.
+func SelectBestIPv6(cfg config.Config, infos []IPv6Info, current string) (string, error) {
+    evals := EvaluateIPv6(cfg, infos, current)
+    valid := 0
+    for _, ev := range evals {
+        if ev.Rank == 1 {
+            return ev.Info.IP.String(), nil
+        }
+        if rejectReason(ev.Info, cfg.GetIP.AllowTemporary) == "" {
+            valid++
         }
     }
 
-    return bestCandidate.IP.String(), nil
+    if valid > 0 {
+        return "", fmt.Errorf("none of the %d DDNS candidates passes 'get_ip.policy'", valid)
+    }
+    return "", errors.New("no suitable DDNS Candidate (Global Unicast, not deprecated) found")
 }
 
-// filterValidAddresses centralizes IPv6 candidate filtering.
-// It returns addresses that are suitable DDNS candidates: non-nil, global unicast,
-// not deprecated, not unique-local, not link-local or loopback, and have non-zero ValidLft.
-func filterValidAddresses(infos []IPv6Info) []IPv6Info {
-    var out []IPv6Info
-    for _, info := range infos {
-        if info.IP == nil {
-            continue
-        }
-        if info.IP.To4() != nil {
-            continue
-        }
-        if info.IP.IsLinkLocalUnicast() || info.IP.IsLoopback() {
-            continue
-        }
-        if info.ValidLft.Seconds() == 0 {
-            continue
-        }
-        // Prefer explicit IsCandidate when present (populated by platform code);
-        // otherwise apply the same rules used by populateInfo.
-        if info.IsCandidate || (info.Scope == "Global Unicast" && !info.IsDeprecated && !info.IsUniqueLocal) {
-            out = append(out, info)
-        }
+// rejectReason centralizes IPv6 candidate filtering.
+// It returns why info is not a suitable DDNS candidate, or "" when it is:
+// non-nil, global unicast, not deprecated, not unique-local, not link-local
+// or loopback, and with non-zero ValidLft. Tentative, dadfailed, anycast and
+// detached addresses are never used; temporary ones only when allowTemporary.
+func rejectReason(info IPv6Info, allowTemporary bool) string {
+    switch {
+    case info.IP == nil:
+        return "no address"
+    case info.IP.To4() != nil:
+        return "IPv4 address"
+    case info.IP.IsLinkLocalUnicast():
+        return "link-local"
+    case info.IP.IsLoopback():
+        return "loopback"
+    case info.ValidLft.Seconds() == 0:
+        return "expired"
+    case info.Flags.Has(FlagTentative):
+        return "tentative, duplicate address detection still running"
+    case info.Flags.Has(FlagDADFailed):
+        return "duplicate address detection failed"
+    case info.Flags.Has(FlagAnycast):
+        return "anycast address"
+    case info.Flags.Has(FlagDetached):
+        return "detached, the prefix is no longer advertised on the link"
+    case info.Flags.Has(FlagTemporary) && !allowTemporary:
+        return "temporary address, get_ip.allow_temporary is off"
+    }
+    // Prefer explicit IsCandidate when present (populated by platform code);
+    // otherwise apply the same rules used by populateInfo.
+    switch {
+    case info.IsCandidate:
+        return ""
+    case info.IsUniqueLocal:
+        return "unique local (ULA)"
+    case info.IsDeprecated:
+        return "deprecated, preferred lifetime expired"
+    case info.Scope != "Global Unicast":
+        return "not global unicast"
     }
-    return out
+    return ""
+}
+
+// IsCandidate reports whether info passes the basic filter, before the
+// selection policy is applied
+func IsCandidate(info IPv6Info, allowTemporary bool) bool {
+    return rejectReason(info, allowTemporary) == ""
 }
 
 // createHTTPClient creates an HTTP client with optional proxy support
 func createHTTPClient(cfg config.Config) (*http.Client, error) {
-    transport := &http.Transport{}
-
-    if cfg.Proxy != "" {
-        proxyURL, err := url.Parse(cfg.Proxy)
-        if err != nil {
-            return nil, err
-        }
-
-        switch proxyURL.Scheme {
-        case "http", "https":
-            transport.Proxy = http.ProxyURL(proxyURL)
-        case "socks5", "socks5h":
-            var auth *xnet.Auth
-            if proxyURL.User != nil {
-                pw, _ := proxyURL.User.Password()
-                username := proxyURL.User.Username()
-                auth = &xnet.Auth{User: username, Password: pw}
-            }
-            dialer, err := xnet.SOCKS5("tcp", proxyURL.Host, auth, xnet.Direct)
-            if err != nil {
-                return nil, err
-            }
-            transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-                return dialer.Dial(network, addr)
-            }
-        default:
-            return nil, errors.New("unsupported proxy scheme: " + proxyURL.Scheme)
-        }
-    }
-
-    return &http.Client{
-        Transport: transport,
-        Timeout:   15 * time.Second,
-    }, nil
+    return httpclient.New(cfg.Proxy, 15*time.Second)
 }
 
 // GetIPv6Fallback queries remote API for an IPv6 address
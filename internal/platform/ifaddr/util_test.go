@@ -0,0 +1,32 @@
+package ifaddr
+
+import (
+    "net"
+    "testing"
+    "time"
+)
+
+func TestRejectReason(t *testing.T) {
+    tests := []struct {
+        flags          AddrFlags
+        allowTemporary bool
+        candidate      bool
+    }{
+        {0, false, true},
+        {FlagPermanent | FlagNoDAD, false, true},
+        {FlagTentative, false, false},
+        {FlagDADFailed, false, false},
+        {FlagAnycast, false, false},
+        {FlagDetached, false, false},
+        {FlagTemporary, false, false},
+        {FlagTemporary, true, true},
+        {FlagTemporary | FlagTentative, true, false},
+    }
+    for _, tt := range tests {
+        info := IPv6Info{IP: net.ParseIP("2001:db8::1"), PreferredLft: time.Hour, ValidLft: 2 * time.Hour, Flags: tt.flags}
+        populateInfo(&info)
+        if got := IsCandidate(info, tt.allowTemporary); got != tt.candidate {
+            t.Errorf("flags %q allowTemporary=%v: candidate %v (%s), want %v", tt.flags, tt.allowTemporary, got, rejectReason(info, tt.allowTemporary), tt.candidate)
+        }
+    }
+}
@@ -0,0 +1,205 @@
+package aliyun
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const (
+	defaultEndpoint = "https://alidns.aliyuncs.com/"
+	apiVersion      = "2015-01-09"
+)
+
+// Provider updates records through the Alibaba Cloud DNS RPC API
+type Provider struct {
+	cfg      config.Config
+	keys     config.AliyunConfig
+	endpoint string
+	client   *http.Client
+}
+
+type domainRecord struct {
+	RecordID string `json:"RecordId"`
+	RR       string `json:"RR"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	TTL      int    `json:"TTL"`
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := defaultEndpoint
+	if cfg.Options.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Options.Endpoint, "/") + "/"
+	}
+	return &Provider{cfg: cfg, keys: *cfg.Options.Aliyun, endpoint: endpoint, client: client}, nil
+}
+
+// percentEncode is the RFC 3986 encoding required by the signature
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// sign computes the HMAC-SHA1 signature of the RPC style request
+func sign(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params.Get(k)))
+	}
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// call invokes action with params and decodes the JSON reply into out
+func (p *Provider) call(action string, params map[string]string, out interface{}) error {
+	query := url.Values{
+		"Action":           {action},
+		"Format":           {"JSON"},
+		"Version":          {apiVersion},
+		"AccessKeyId":      {p.keys.AccessKeyID},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureVersion": {"1.0"},
+		"SignatureNonce":   {nonce()},
+		"Timestamp":        {time.Now().UTC().Format("2006-01-02T15:04:05Z")},
+	}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set("Signature", sign("GET", query, p.keys.AccessKeySecret))
+
+	resp, err := p.client.Get(p.endpoint + "?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		}
+		json.Unmarshal(raw, &apiErr)
+		return fmt.Errorf("Aliyun DNS %s failed (HTTP %d, %s): %s", action, resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode Aliyun DNS %s response: %w", action, err)
+	}
+	return nil
+}
+
+// records returns the AAAA records of the configured name
+func (p *Provider) records() ([]domainRecord, error) {
+	var result struct {
+		DomainRecords struct {
+			Record []domainRecord `json:"Record"`
+		} `json:"DomainRecords"`
+	}
+	err := p.call("DescribeSubDomainRecords", map[string]string{
+		"SubDomain":  p.cfg.Options.Domain.Record + "." + p.cfg.Options.Domain.Zone,
+		"DomainName": p.cfg.Options.Domain.Zone,
+		"Type":       "AAAA",
+		"PageSize":   "500",
+	}, &result)
+	return result.DomainRecords.Record, err
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	records, err := p.records()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, r := range records {
+		ips = append(ips, r.Value)
+	}
+	return ips, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	records, err := p.records()
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+
+	params := map[string]string{
+		"RR":    p.cfg.Options.Domain.Record,
+		"Type":  "AAAA",
+		"Value": ip,
+		"TTL":   strconv.Itoa(p.cfg.Options.TTL),
+	}
+	if len(records) == 0 {
+		params["DomainName"] = p.cfg.Options.Domain.Zone
+		return p.call("AddDomainRecord", params, nil)
+	}
+
+	// 优先保留已是该地址的记录，删除其余同名 AAAA 记录，使 RRset 只包含 ip
+	keep := 0
+	for i, r := range records {
+		if r.Value == ip {
+			keep = i
+			break
+		}
+	}
+	// 内容未变化时 UpdateDomainRecord 会返回 DomainRecordDuplicate
+	if existing := records[keep]; existing.Value != ip || existing.TTL != p.cfg.Options.TTL {
+		params["RecordId"] = existing.RecordID
+		if err := p.call("UpdateDomainRecord", params, nil); err != nil {
+			return err
+		}
+	}
+	for i, r := range records {
+		if i == keep {
+			continue
+		}
+		if err := p.call("DeleteDomainRecord", map[string]string{"RecordId": r.RecordID}, nil); err != nil {
+			return fmt.Errorf("failed to delete extra record %s: %w", r.Value, err)
+		}
+	}
+	return nil
+}
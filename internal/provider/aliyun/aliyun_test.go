@@ -0,0 +1,179 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeAPI is an in-memory stand-in for the RPC endpoint. It checks the
+// signature of every request.
+type fakeAPI struct {
+	mu      sync.Mutex
+	records []domainRecord
+	nextID  int
+	actions []string
+}
+
+func newFakeAPI(t *testing.T, records ...domainRecord) (*fakeAPI, string) {
+	t.Helper()
+	f := &fakeAPI{records: records, nextID: 100}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		q := r.URL.Query()
+		signature := q.Get("Signature")
+		q.Del("Signature")
+		if q.Get("AccessKeyId") != "id" || signature != sign("GET", q, "secret") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "SignatureDoesNotMatch", "Message": "Specified signature is not matched with our calculation."})
+			return
+		}
+		action := q.Get("Action")
+		if action != "DescribeSubDomainRecords" {
+			f.actions = append(f.actions, action)
+		}
+		switch action {
+		case "DescribeSubDomainRecords":
+			var found []domainRecord
+			for _, rec := range f.records {
+				if rec.Type == q.Get("Type") && rec.RR+"."+q.Get("DomainName") == q.Get("SubDomain") {
+					found = append(found, rec)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"TotalCount": len(found), "DomainRecords": map[string]interface{}{"Record": found}})
+		case "AddDomainRecord":
+			ttl, _ := strconv.Atoi(q.Get("TTL"))
+			rec := domainRecord{RecordID: fmt.Sprint(f.nextID), RR: q.Get("RR"), Type: q.Get("Type"), Value: q.Get("Value"), TTL: ttl}
+			f.nextID++
+			f.records = append(f.records, rec)
+			json.NewEncoder(w).Encode(map[string]string{"RecordId": rec.RecordID})
+		case "UpdateDomainRecord":
+			for i := range f.records {
+				rec := &f.records[i]
+				if rec.RecordID != q.Get("RecordId") {
+					continue
+				}
+				ttl, _ := strconv.Atoi(q.Get("TTL"))
+				if rec.Value == q.Get("Value") && rec.TTL == ttl {
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]string{"Code": "DomainRecordDuplicate", "Message": "The DNS record already exists."})
+					return
+				}
+				rec.RR, rec.Value, rec.TTL = q.Get("RR"), q.Get("Value"), ttl
+				json.NewEncoder(w).Encode(map[string]string{"RecordId": rec.RecordID})
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "DomainRecordNotBelongToUser", "Message": "The DNS record does not exist."})
+		case "DeleteDomainRecord":
+			for i := range f.records {
+				if f.records[i].RecordID == q.Get("RecordId") {
+					f.records = append(f.records[:i], f.records[i+1:]...)
+					break
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]string{"RecordId": q.Get("RecordId")})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "InvalidAction.NotFound", "Message": "Specified api is not found."})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+// state returns the AAAA contents of home and the write actions since the
+// last call
+func (f *fakeAPI) state() ([]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ips []string
+	for _, r := range f.records {
+		if r.Type == "AAAA" && r.RR == "home" {
+			ips = append(ips, r.Value)
+		}
+	}
+	sort.Strings(ips)
+	actions := f.actions
+	f.actions = nil
+	return ips, actions
+}
+
+func newTestProvider(t *testing.T, endpoint, secret string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "aliyun"}
+	cfg.Options.TTL = 600
+	cfg.Options.Endpoint = endpoint
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.Aliyun = &config.AliyunConfig{AccessKeyID: "id", AccessKeySecret: secret}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAliyunUpsert(t *testing.T) {
+	f, endpoint := newFakeAPI(t, domainRecord{RecordID: "1", RR: "home", Type: "A", Value: "192.0.2.1", TTL: 600})
+	p := newTestProvider(t, endpoint, "secret")
+
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if ips, actions := f.state(); !reflect.DeepEqual(ips, []string{"2001:db8::1"}) || !reflect.DeepEqual(actions, []string{"AddDomainRecord"}) {
+		t.Fatalf("create: records %v, actions %v", ips, actions)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if ips, actions := f.state(); !reflect.DeepEqual(ips, []string{"2001:db8::2"}) || !reflect.DeepEqual(actions, []string{"UpdateDomainRecord"}) {
+		t.Fatalf("update: records %v, actions %v", ips, actions)
+	}
+
+	// 内容未变时不调用 UpdateDomainRecord，否则会返回 DomainRecordDuplicate
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, actions := f.state(); len(actions) != 0 {
+		t.Errorf("unchanged record written: %v", actions)
+	}
+}
+
+func TestAliyunCollapsesRRset(t *testing.T) {
+	f, endpoint := newFakeAPI(t,
+		domainRecord{RecordID: "1", RR: "home", Type: "AAAA", Value: "2001:db8::1", TTL: 600},
+		domainRecord{RecordID: "2", RR: "home", Type: "AAAA", Value: "2001:db8::2", TTL: 600},
+		domainRecord{RecordID: "3", RR: "home", Type: "AAAA", Value: "2001:db8::3", TTL: 600},
+	)
+	p := newTestProvider(t, endpoint, "secret")
+
+	if ips, err := p.GetRecord(); err != nil || len(ips) != 3 {
+		t.Fatalf("GetRecord = %v, %v", ips, err)
+	}
+	if err := p.UpsertRecord("2001:db8::3"); err != nil {
+		t.Fatal(err)
+	}
+	ips, actions := f.state()
+	if !reflect.DeepEqual(ips, []string{"2001:db8::3"}) || !reflect.DeepEqual(actions, []string{"DeleteDomainRecord", "DeleteDomainRecord"}) {
+		t.Errorf("records %v, actions %v", ips, actions)
+	}
+}
+
+func TestAliyunError(t *testing.T) {
+	_, endpoint := newFakeAPI(t)
+	err := newTestProvider(t, endpoint, "wrong").UpsertRecord("2001:db8::1")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 400, SignatureDoesNotMatch") {
+		t.Errorf("bad secret: %v", err)
+	}
+}
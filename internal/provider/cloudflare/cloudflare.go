@@ -2,36 +2,38 @@ package cloudflare
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
-	xnet "golang.org/x/net/proxy"
-
 	"goddns/internal/config"
+	"goddns/internal/httpclient"
 )
 
 // CloudflareProvider implements Cloudflare-specific logic
 type CloudflareProvider struct {
 	Config config.Config
+
+	zonesEndpoint string
+	zoneID        string // 缓存查询到的 Zone ID
 }
 
 const (
 	cloudflareAPI  = "https://api.cloudflare.com/client/v4"
-	zonesEndpoint  = cloudflareAPI + "/zones"
 	defaultRetries = 3
 	baseDelay      = 1 * time.Second
 )
 
 // NewProvider constructor
 func NewProvider(cfg config.Config) *CloudflareProvider {
-	return &CloudflareProvider{Config: cfg}
+	api := cloudflareAPI
+	if cfg.Options.Endpoint != "" {
+		api = strings.TrimRight(cfg.Options.Endpoint, "/")
+	}
+	return &CloudflareProvider{Config: cfg, zonesEndpoint: api + "/zones"}
 }
 
 // cfRequest with retry
@@ -48,37 +50,13 @@ func (p *CloudflareProvider) cfRequest(method string, endpoint string, data inte
 			return nil, err
 		}
 
-		req.Header.Set("Authorization", "Bearer "+p.Config.Cloudflare.APIToken)
+		req.Header.Set("Authorization", "Bearer "+p.Config.Options.APIToken)
 		req.Header.Set("Content-Type", "application/json")
 
-		transport := &http.Transport{}
-		if p.Config.Proxy != "" {
-			u, err := url.Parse(p.Config.Proxy)
-			if err != nil || u.Scheme == "" {
-				return nil, fmt.Errorf("invalid proxy URL '%s': must include scheme (e.g. 'http://', 'https://', 'socks5://')", p.Config.Proxy)
-			}
-
-			switch strings.ToLower(u.Scheme) {
-			case "http", "https":
-				transport.Proxy = http.ProxyURL(u)
-			case "socks5", "socks5h":
-				var auth *xnet.Auth
-				if u.User != nil {
-					pw, _ := u.User.Password()
-					auth = &xnet.Auth{User: u.User.Username(), Password: pw}
-				}
-				dialer, err := xnet.SOCKS5("tcp", u.Host, auth, xnet.Direct)
-				if err != nil {
-					return nil, fmt.Errorf("failed to create socks5 dialer: %w", err)
-				}
-				transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return dialer.Dial(network, addr)
-				}
-			default:
-				return nil, fmt.Errorf("unsupported proxy scheme '%s' in proxy url", u.Scheme)
-			}
+		client, err := httpclient.New(p.Config.Proxy, httpclient.DefaultTimeout)
+		if err != nil {
+			return nil, err
 		}
-		client := &http.Client{Timeout: 15 * time.Second, Transport: transport}
 		resp, err := client.Do(req)
 		if err != nil {
 			if attempt == defaultRetries {
@@ -101,7 +79,7 @@ func (p *CloudflareProvider) cfRequest(method string, endpoint string, data inte
 
 // GetZoneID returns the Cloudflare Zone ID for the configured zone
 func (p *CloudflareProvider) GetZoneID(cfg config.Config) (string, error) {
-	reqURL := zonesEndpoint + "?name=" + cfg.Cloudflare.Domain.Zone
+	reqURL := p.zonesEndpoint + "?name=" + cfg.Options.Domain.Zone
 	resp, err := p.cfRequest("GET", reqURL, nil)
 	if err != nil {
 		return "", err
@@ -128,69 +106,136 @@ func (p *CloudflareProvider) GetZoneID(cfg config.Config) (string, error) {
 		if len(result.Errors) > 0 {
 			errMsg = fmt.Sprintf("Code %d: %s", result.Errors[0].Code, result.Errors[0].Message)
 		}
-		return "", fmt.Errorf("failed to find zone %s. API error: %s", cfg.Cloudflare.Domain.Zone, errMsg)
+		return "", fmt.Errorf("failed to find zone %s. API error: %s", cfg.Options.Domain.Zone, errMsg)
 	}
 
 	return result.Result[0].ID, nil
 }
 
-// UpsertDNSRecord creates or updates the DNS record
-func (p *CloudflareProvider) UpsertDNSRecord(cfg config.Config, ip string, zoneID string) (bool, error) {
-	fqdn := cfg.Cloudflare.Domain.Record + "." + cfg.Cloudflare.Domain.Zone
-	recordType := "AAAA"
+// zone returns the configured Zone ID, looking it up once when not set
+func (p *CloudflareProvider) zone() (string, error) {
+	if p.Config.Options.ZoneID != "" {
+		return p.Config.Options.ZoneID, nil
+	}
+	if p.zoneID == "" {
+		zoneID, err := p.GetZoneID(p.Config)
+		if err != nil {
+			return "", err
+		}
+		p.zoneID = zoneID
+	}
+	return p.zoneID, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *CloudflareProvider) GetRecord() ([]string, error) {
+	zoneID, err := p.zone()
+	if err != nil {
+		return nil, err
+	}
+	content, err := p.GetDNSRecord(p.Config, zoneID)
+	if err != nil || content == "" {
+		return nil, err
+	}
+	return []string{content}, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *CloudflareProvider) UpsertRecord(ip string) error {
+	zoneID, err := p.zone()
+	if err != nil {
+		return err
+	}
+	_, err = p.UpsertDNSRecord(p.Config, ip, zoneID)
+	return err
+}
+
+// dnsRecord is an existing record as returned by the search endpoint
+type dnsRecord struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Proxied bool   `json:"proxied"`
+	TTL     int    `json:"ttl"`
+}
 
-	searchURL := fmt.Sprintf("%s/%s/dns_records?type=%s&name=%s", zonesEndpoint, zoneID, recordType, fqdn)
+// findRecord returns the AAAA record named fqdn, or nil if it does not exist
+func (p *CloudflareProvider) findRecord(zoneID string, fqdn string) (*dnsRecord, error) {
+	searchURL := fmt.Sprintf("%s/%s/dns_records?type=%s&name=%s", p.zonesEndpoint, zoneID, "AAAA", fqdn)
 	resp, err := p.cfRequest("GET", searchURL, nil)
 	if err != nil {
-		return false, fmt.Errorf("failed to search existing DNS record: %w", err)
+		return nil, fmt.Errorf("failed to search existing DNS record: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var searchResult struct {
-		Success bool `json:"success"`
-		Result  []struct {
-			ID      string `json:"id"`
-			Content string `json:"content"`
-			Proxied bool   `json:"proxied"`
-			TTL     int    `json:"ttl"`
-		} `json:"result"`
-		Errors []struct {
+		Success bool        `json:"success"`
+		Result  []dnsRecord `json:"result"`
+		Errors  []struct {
 			Message string `json:"message"`
 		} `json:"errors"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&searchResult); err != nil {
-		return false, fmt.Errorf("failed to decode DNS search response: %w", err)
+		return nil, fmt.Errorf("failed to decode DNS search response: %w", err)
 	}
 
 	if !searchResult.Success {
-		return false, fmt.Errorf("DNS search failed. API error: %s", searchResult.Errors[0].Message)
+		errMsg := "unknown error"
+		if len(searchResult.Errors) > 0 {
+			errMsg = searchResult.Errors[0].Message
+		}
+		return nil, fmt.Errorf("DNS search failed. API error: %s", errMsg)
+	}
+
+	if len(searchResult.Result) == 0 {
+		return nil, nil
+	}
+	return &searchResult.Result[0], nil
+}
+
+// GetDNSRecord returns the content of the configured record, or "" if it does not exist
+func (p *CloudflareProvider) GetDNSRecord(cfg config.Config, zoneID string) (string, error) {
+	fqdn := cfg.RecordName()
+	existing, err := p.findRecord(zoneID, fqdn)
+	if err != nil || existing == nil {
+		return "", err
+	}
+	return existing.Content, nil
+}
+
+// UpsertDNSRecord creates or updates the DNS record
+func (p *CloudflareProvider) UpsertDNSRecord(cfg config.Config, ip string, zoneID string) (bool, error) {
+	fqdn := cfg.RecordName()
+	recordType := "AAAA"
+
+	existing, err := p.findRecord(zoneID, fqdn)
+	if err != nil {
+		return false, err
 	}
 
 	newRecordData := map[string]interface{}{
 		"type":    recordType,
 		"name":    fqdn,
 		"content": ip,
-		"ttl":     cfg.Cloudflare.TTL,
-		"proxied": cfg.Cloudflare.Proxied,
+		"ttl":     cfg.Options.TTL,
+		"proxied": cfg.Options.Proxied,
 	}
 
 	var method, apiEndpoint string
 
-	if len(searchResult.Result) > 0 {
-		existing := searchResult.Result[0]
-		if existing.Content == ip && existing.Proxied == cfg.Cloudflare.Proxied && existing.TTL == cfg.Cloudflare.TTL {
+	if existing != nil {
+		if existing.Content == ip && existing.Proxied == cfg.Options.Proxied && existing.TTL == cfg.Options.TTL {
 			return true, nil
 		}
 		recordID := existing.ID
 		method = "PUT"
-		apiEndpoint = fmt.Sprintf("%s/%s/dns_records/%s", zonesEndpoint, zoneID, recordID)
+		apiEndpoint = fmt.Sprintf("%s/%s/dns_records/%s", p.zonesEndpoint, zoneID, recordID)
 	} else {
 		method = "POST"
-		apiEndpoint = fmt.Sprintf("%s/%s/dns_records", zonesEndpoint, zoneID)
+		apiEndpoint = fmt.Sprintf("%s/%s/dns_records", p.zonesEndpoint, zoneID)
 	}
 
-	resp, err = p.cfRequest(method, apiEndpoint, newRecordData)
+	resp, err := p.cfRequest(method, apiEndpoint, newRecordData)
 	if err != nil {
 		return false, fmt.Errorf("API call failed during %s: %w", method, err)
 	}
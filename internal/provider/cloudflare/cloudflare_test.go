@@ -0,0 +1,147 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeAPI is an in-memory stand-in for the zones and dns_records endpoints
+type fakeAPI struct {
+	mu      sync.Mutex
+	records []dnsRecord
+	nextID  int
+	calls   []string
+}
+
+func newFakeAPI(t *testing.T, records ...dnsRecord) (*fakeAPI, string) {
+	t.Helper()
+	f := &fakeAPI{records: records, nextID: len(records) + 1}
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result})
+	}
+	mux.HandleFunc("GET /zones", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "example.com" {
+			reply(w, []interface{}{})
+			return
+		}
+		reply(w, []map[string]string{{"id": "zone1"}})
+	})
+	mux.HandleFunc("GET /zones/zone1/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		var found []dnsRecord
+		if r.URL.Query().Get("type") == "AAAA" && r.URL.Query().Get("name") == "home.example.com" {
+			found = f.records
+		}
+		reply(w, found)
+	})
+	mux.HandleFunc("POST /zones/zone1/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		var rec dnsRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = fmt.Sprint(f.nextID)
+		f.nextID++
+		f.records = append(f.records, rec)
+		reply(w, rec)
+	})
+	mux.HandleFunc("PUT /zones/zone1/dns_records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for i := range f.records {
+			if f.records[i].ID == r.PathValue("id") {
+				json.NewDecoder(r.Body).Decode(&f.records[i])
+				f.records[i].ID = r.PathValue("id")
+				reply(w, f.records[i])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"code": 81044, "message": "Record does not exist."}}})
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"code": 9109, "message": "Invalid access token"}}})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+func (f *fakeAPI) state() ([]dnsRecord, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = nil
+	return append([]dnsRecord(nil), f.records...), calls
+}
+
+func newTestProvider(endpoint, token string) *CloudflareProvider {
+	cfg := config.Config{Provider: "cloudflare"}
+	cfg.Options.APIToken = token
+	cfg.Options.TTL = 300
+	cfg.Options.Endpoint = endpoint
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	return NewProvider(cfg)
+}
+
+func TestCloudflareUpsert(t *testing.T) {
+	f, endpoint := newFakeAPI(t)
+	p := newTestProvider(endpoint, "token")
+
+	if ips, err := p.GetRecord(); err != nil || len(ips) != 0 {
+		t.Fatalf("GetRecord on empty zone = %v, %v", ips, err)
+	}
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	records, calls := f.state()
+	// Zone ID 只查询一次
+	want := []string{"GET /zones", "GET /zones/zone1/dns_records", "GET /zones/zone1/dns_records", "POST /zones/zone1/dns_records"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+	if len(records) != 1 || records[0].Content != "2001:db8::1" || records[0].TTL != 300 {
+		t.Fatalf("records after create: %+v", records)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if records, calls := f.state(); len(records) != 1 || records[0].Content != "2001:db8::2" || calls[len(calls)-1] != "PUT /zones/zone1/dns_records/1" {
+		t.Errorf("update: records %+v, calls %v", records, calls)
+	}
+
+	// 内容未变时不写入
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, calls := f.state(); len(calls) != 1 {
+		t.Errorf("unchanged record: calls %v", calls)
+	}
+	if ips, err := p.GetRecord(); err != nil || !reflect.DeepEqual(ips, []string{"2001:db8::2"}) {
+		t.Errorf("GetRecord = %v, %v", ips, err)
+	}
+}
+
+func TestCloudflareErrors(t *testing.T) {
+	_, endpoint := newFakeAPI(t)
+	if err := newTestProvider(endpoint, "wrong").UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "Invalid access token") {
+		t.Errorf("bad token: %v", err)
+	}
+
+	p := newTestProvider(endpoint, "token")
+	p.Config.Options.Domain.Zone = "example.org"
+	if err := p.UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "failed to find zone example.org") {
+		t.Errorf("unknown zone: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package desec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const (
+	defaultEndpoint = "https://desec.io/api/v1"
+	defaultRetries  = 3
+	// maxRetryAfter 超过该等待时间的限流直接报错，交给下一次检测重试
+	maxRetryAfter = 60 * time.Second
+)
+
+// Provider updates records through the deSEC API
+type Provider struct {
+	cfg      config.Config
+	endpoint string
+	client   *http.Client
+}
+
+type rrset struct {
+	Subname string   `json:"subname"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	Records []string `json:"records"`
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := defaultEndpoint
+	if cfg.Options.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Options.Endpoint, "/")
+	}
+	return &Provider{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// retryAfter parses the Retry-After header, which deSEC sends in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 1 {
+		return time.Second
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// request sends an API request and decodes the JSON reply into out when given.
+// It returns found=false for 404. Throttled requests (HTTP 429) are retried
+// after the delay announced by the server when it is short enough.
+func (p *Provider) request(method, endpoint string, data interface{}, out interface{}) (found bool, err error) {
+	var payload []byte
+	if data != nil {
+		payload, _ = json.Marshal(data)
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Token "+p.cfg.Options.APIToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("API request failed: %w", err)
+		}
+		raw, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return false, nil
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := retryAfter(resp)
+			if attempt < defaultRetries && wait <= maxRetryAfter {
+				time.Sleep(wait)
+				continue
+			}
+			return false, fmt.Errorf("deSEC API %s rate limited, retry after %s", method, wait)
+		case resp.StatusCode >= 300:
+			var apiErr struct {
+				Detail string `json:"detail"`
+			}
+			json.Unmarshal(raw, &apiErr)
+			msg := apiErr.Detail
+			if msg == "" {
+				msg = strings.TrimSpace(string(raw))
+			}
+			return false, fmt.Errorf("deSEC API %s failed (HTTP %d): %s", method, resp.StatusCode, msg)
+		}
+
+		if out != nil {
+			if err := json.Unmarshal(raw, out); err != nil {
+				return true, fmt.Errorf("failed to decode deSEC API response: %w", err)
+			}
+		}
+		return true, nil
+	}
+}
+
+func (p *Provider) rrsetsURL() string {
+	return fmt.Sprintf("%s/domains/%s/rrsets/", p.endpoint, url.PathEscape(p.cfg.Options.Domain.Zone))
+}
+
+// current returns the AAAA RRset of the configured name, nil if it does not exist
+func (p *Provider) current() (*rrset, error) {
+	var set rrset
+	found, err := p.request("GET", p.rrsetsURL()+url.PathEscape(p.cfg.Options.Domain.Record)+"/AAAA/", nil, &set)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	set, err := p.current()
+	if err != nil || set == nil {
+		return nil, err
+	}
+	return set.Records, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	set, err := p.current()
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+	if set != nil && len(set.Records) == 1 && set.Records[0] == ip && set.TTL == p.cfg.Options.TTL {
+		return nil
+	}
+
+	// 批量 PUT 会创建或整体替换列出的 RRset，不影响其他记录
+	subname := p.cfg.Options.Domain.Record
+	if subname == config.ApexRecord {
+		subname = ""
+	}
+	update := []rrset{{Subname: subname, Type: "AAAA", TTL: p.cfg.Options.TTL, Records: []string{ip}}}
+	_, err = p.request("PUT", p.rrsetsURL(), update, nil)
+	return err
+}
@@ -0,0 +1,175 @@
+package desec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeAPI is an in-memory stand-in for the rrsets endpoints of one domain
+type fakeAPI struct {
+	mu       sync.Mutex
+	rrsets   map[string]rrset // subname → AAAA RRset
+	throttle []string         // 依次返回 429 时的 Retry-After
+	writes   []rrset
+	requests int
+}
+
+func newFakeAPI(t *testing.T, rrsets ...rrset) (*fakeAPI, string) {
+	t.Helper()
+	f := &fakeAPI{rrsets: map[string]rrset{}}
+	for _, set := range rrsets {
+		f.rrsets[set.Subname] = set
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /domains/example.com/rrsets/{subname}/AAAA/", func(w http.ResponseWriter, r *http.Request) {
+		set, ok := f.rrsets[r.PathValue("subname")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Not found."})
+			return
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("PUT /domains/example.com/rrsets/", func(w http.ResponseWriter, r *http.Request) {
+		var update []rrset
+		json.NewDecoder(r.Body).Decode(&update)
+		for _, set := range update {
+			if set.TTL < 3600 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode([]map[string][]string{{"ttl": {"Ensure this value is greater than or equal to 3600."}}})
+				return
+			}
+			f.rrsets[set.Subname] = set
+			f.writes = append(f.writes, set)
+		}
+		json.NewEncoder(w).Encode(update)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests++
+		if r.Header.Get("Authorization") != "Token token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Invalid token."})
+			return
+		}
+		if len(f.throttle) > 0 {
+			w.Header().Set("Retry-After", f.throttle[0])
+			f.throttle = f.throttle[1:]
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Request was throttled."})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+// state returns the RRsets written and the number of requests since the
+// last call
+func (f *fakeAPI) state() ([]rrset, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writes, n := f.writes, f.requests
+	f.writes, f.requests = nil, 0
+	return writes, n
+}
+
+func (f *fakeAPI) throttleNext(retryAfter ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.throttle = retryAfter
+}
+
+func newTestProvider(t *testing.T, endpoint, token, record string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "desec"}
+	cfg.Options.APIToken = token
+	cfg.Options.TTL = 3600
+	cfg.Options.Endpoint = endpoint + "/"
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", record
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDesecUpsert(t *testing.T) {
+	f, endpoint := newFakeAPI(t, rrset{Subname: "home", Type: "AAAA", TTL: 3600, Records: []string{"2001:db8::1", "2001:db8::2"}})
+	p := newTestProvider(t, endpoint, "token", "home")
+
+	if ips, err := p.GetRecord(); err != nil || !reflect.DeepEqual(ips, []string{"2001:db8::1", "2001:db8::2"}) {
+		t.Fatalf("GetRecord = %v, %v", ips, err)
+	}
+	// RRset 中有多个地址时整体替换为 ip
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	want := []rrset{{Subname: "home", Type: "AAAA", TTL: 3600, Records: []string{"2001:db8::1"}}}
+	if writes, _ := f.state(); !reflect.DeepEqual(writes, want) {
+		t.Errorf("writes %+v, want %+v", writes, want)
+	}
+
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if writes, _ := f.state(); len(writes) != 0 {
+		t.Errorf("unchanged RRset written: %+v", writes)
+	}
+
+	// 顶点记录使用空的 subname
+	apex := newTestProvider(t, endpoint, "token", config.ApexRecord)
+	if ips, err := apex.GetRecord(); err != nil || ips != nil {
+		t.Fatalf("GetRecord of missing RRset = %v, %v", ips, err)
+	}
+	if err := apex.UpsertRecord("2001:db8::9"); err != nil {
+		t.Fatal(err)
+	}
+	if writes, _ := f.state(); len(writes) != 1 || writes[0].Subname != "" {
+		t.Errorf("apex writes %+v", writes)
+	}
+}
+
+func TestDesecThrottled(t *testing.T) {
+	f, endpoint := newFakeAPI(t)
+	p := newTestProvider(t, endpoint, "token", "home")
+
+	f.throttleNext("1")
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if writes, n := f.state(); len(writes) != 1 || n != 3 {
+		t.Errorf("short throttle: writes %+v after %d requests", writes, n)
+	}
+
+	// 等待时间过长时不重试
+	f.throttleNext("120")
+	if err := p.UpsertRecord("2001:db8::2"); err == nil || !strings.Contains(err.Error(), "rate limited, retry after 2m0s") {
+		t.Errorf("long throttle: %v", err)
+	}
+	if _, n := f.state(); n != 1 {
+		t.Errorf("long throttle: %d requests", n)
+	}
+}
+
+func TestDesecErrors(t *testing.T) {
+	_, endpoint := newFakeAPI(t)
+	if err := newTestProvider(t, endpoint, "wrong", "home").UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "HTTP 401): Invalid token.") {
+		t.Errorf("bad token: %v", err)
+	}
+
+	p := newTestProvider(t, endpoint, "token", "home")
+	p.cfg.Options.TTL = 60
+	if err := p.UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "greater than or equal to 3600") {
+		t.Errorf("low TTL: %v", err)
+	}
+}
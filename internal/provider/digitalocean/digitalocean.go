@@ -0,0 +1,165 @@
+package digitalocean
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const defaultEndpoint = "https://api.digitalocean.com/v2"
+
+// Provider updates records through the DigitalOcean API
+type Provider struct {
+	cfg      config.Config
+	endpoint string
+	fqdn     string
+	client   *http.Client
+}
+
+type domainRecord struct {
+	ID   int    `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl"`
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := defaultEndpoint
+	if cfg.Options.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Options.Endpoint, "/")
+	}
+	return &Provider{
+		cfg:      cfg,
+		endpoint: endpoint,
+		fqdn:     cfg.RecordName(),
+		client:   client,
+	}, nil
+}
+
+// request sends an API request and decodes the JSON reply into out when given
+func (p *Provider) request(method, endpoint string, data interface{}, out interface{}) error {
+	var body io.Reader
+	if data != nil {
+		jsonBody, _ := json.Marshal(data)
+		body = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.Options.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("DigitalOcean API %s failed (HTTP %d, %s): %s", method, resp.StatusCode, apiErr.ID, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode DigitalOcean API response: %w", err)
+	}
+	return nil
+}
+
+// records returns the AAAA records of the configured name, following pagination
+func (p *Provider) records() ([]domainRecord, error) {
+	query := url.Values{"type": {"AAAA"}, "name": {p.fqdn}, "per_page": {"200"}}
+	next := fmt.Sprintf("%s/domains/%s/records?%s", p.endpoint, url.PathEscape(p.cfg.Options.Domain.Zone), query.Encode())
+
+	var all []domainRecord
+	for next != "" {
+		var page struct {
+			Records []domainRecord `json:"domain_records"`
+			Links   struct {
+				Pages struct {
+					Next string `json:"next"`
+				} `json:"pages"`
+			} `json:"links"`
+		}
+		if err := p.request("GET", next, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Records {
+			if r.Type == "AAAA" && (r.Name == p.cfg.Options.Domain.Record || strings.EqualFold(r.Name, p.fqdn)) {
+				all = append(all, r)
+			}
+		}
+		next = page.Links.Pages.Next
+	}
+	return all, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	records, err := p.records()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, r := range records {
+		ips = append(ips, r.Data)
+	}
+	return ips, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	records, err := p.records()
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+
+	rec := domainRecord{Type: "AAAA", Name: p.cfg.Options.Domain.Record, Data: ip, TTL: p.cfg.Options.TTL}
+	recordsURL := fmt.Sprintf("%s/domains/%s/records", p.endpoint, url.PathEscape(p.cfg.Options.Domain.Zone))
+	if len(records) == 0 {
+		return p.request("POST", recordsURL, rec, nil)
+	}
+
+	// 优先保留已是该地址的记录，删除其余同名 AAAA 记录，使 RRset 只包含 ip
+	keep := 0
+	for i, r := range records {
+		if r.Data == ip {
+			keep = i
+			break
+		}
+	}
+	if existing := records[keep]; existing.Data != ip || existing.TTL != p.cfg.Options.TTL {
+		if err := p.request("PUT", fmt.Sprintf("%s/%d", recordsURL, existing.ID), rec, nil); err != nil {
+			return err
+		}
+	}
+	for i, r := range records {
+		if i == keep {
+			continue
+		}
+		if err := p.request("DELETE", fmt.Sprintf("%s/%d", recordsURL, r.ID), nil, nil); err != nil {
+			return fmt.Errorf("failed to delete extra record %s: %w", r.Data, err)
+		}
+	}
+	return nil
+}
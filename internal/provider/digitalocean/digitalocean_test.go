@@ -0,0 +1,189 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeAPI is an in-memory stand-in for the domain records endpoints. It
+// returns one record per page to exercise pagination.
+type fakeAPI struct {
+	mu      sync.Mutex
+	url     string
+	records []domainRecord
+	nextID  int
+	writes  []string
+}
+
+func newFakeAPI(t *testing.T, records ...domainRecord) *fakeAPI {
+	t.Helper()
+	f := &fakeAPI{records: records, nextID: 100}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /domains/example.com/records", func(w http.ResponseWriter, r *http.Request) {
+		var found []domainRecord
+		for _, rec := range f.records {
+			if rec.Type == r.URL.Query().Get("type") && rec.Name+".example.com" == r.URL.Query().Get("name") {
+				found = append(found, rec)
+			}
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		reply := map[string]interface{}{"domain_records": []domainRecord{}}
+		if page <= len(found) {
+			reply["domain_records"] = found[page-1 : page]
+		}
+		if page < len(found) {
+			q := r.URL.Query()
+			q.Set("page", strconv.Itoa(page+1))
+			reply["links"] = map[string]interface{}{"pages": map[string]string{"next": f.url + r.URL.Path + "?" + q.Encode()}}
+		}
+		json.NewEncoder(w).Encode(reply)
+	})
+	mux.HandleFunc("POST /domains/example.com/records", func(w http.ResponseWriter, r *http.Request) {
+		var rec domainRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = f.nextID
+		f.nextID++
+		f.records = append(f.records, rec)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"domain_record": rec})
+	})
+	mux.HandleFunc("PUT /domains/example.com/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for i := range f.records {
+			if fmt.Sprint(f.records[i].ID) == r.PathValue("id") {
+				json.NewDecoder(r.Body).Decode(&f.records[i])
+				json.NewEncoder(w).Encode(map[string]interface{}{"domain_record": f.records[i]})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"id": "not_found", "message": "The resource you were accessing could not be found."})
+	})
+	mux.HandleFunc("DELETE /domains/example.com/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for i := range f.records {
+			if fmt.Sprint(f.records[i].ID) == r.PathValue("id") {
+				f.records = append(f.records[:i], f.records[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"id": "unauthorized", "message": "Unable to authenticate you"})
+			return
+		}
+		if r.Method != "GET" {
+			f.writes = append(f.writes, r.Method+" "+r.URL.Path)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	return f
+}
+
+// state returns the AAAA contents and the write requests since the last call
+func (f *fakeAPI) state() ([]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ips []string
+	for _, r := range f.records {
+		if r.Type == "AAAA" && r.Name == "home" {
+			ips = append(ips, r.Data)
+		}
+	}
+	sort.Strings(ips)
+	writes := f.writes
+	f.writes = nil
+	return ips, writes
+}
+
+func newTestProvider(t *testing.T, endpoint, token string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "digitalocean"}
+	cfg.Options.APIToken = token
+	cfg.Options.TTL = 300
+	cfg.Options.Endpoint = endpoint
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDigitalOceanUpsert(t *testing.T) {
+	f := newFakeAPI(t, domainRecord{ID: 1, Type: "A", Name: "home", Data: "192.0.2.1", TTL: 300})
+	p := newTestProvider(t, f.url, "token")
+
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if ips, writes := f.state(); !reflect.DeepEqual(ips, []string{"2001:db8::1"}) || !reflect.DeepEqual(writes, []string{"POST /domains/example.com/records"}) {
+		t.Fatalf("create: records %v, writes %v", ips, writes)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if ips, writes := f.state(); !reflect.DeepEqual(ips, []string{"2001:db8::2"}) || !reflect.DeepEqual(writes, []string{"PUT /domains/example.com/records/100"}) {
+		t.Fatalf("update: records %v, writes %v", ips, writes)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, writes := f.state(); len(writes) != 0 {
+		t.Errorf("unchanged record written: %v", writes)
+	}
+}
+
+func TestDigitalOceanCollapsesRRset(t *testing.T) {
+	f := newFakeAPI(t,
+		domainRecord{ID: 1, Type: "AAAA", Name: "home", Data: "2001:db8::1", TTL: 300},
+		domainRecord{ID: 2, Type: "AAAA", Name: "home", Data: "2001:db8::2", TTL: 300},
+		domainRecord{ID: 3, Type: "AAAA", Name: "home", Data: "2001:db8::3", TTL: 300},
+	)
+	p := newTestProvider(t, f.url, "token")
+
+	// 多页中的全部记录都能读到
+	ips, err := p.GetRecord()
+	if err != nil || len(ips) != 3 {
+		t.Fatalf("GetRecord = %v, %v", ips, err)
+	}
+
+	// 保留已是新地址的记录，删除其余记录
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	ips, writes := f.state()
+	if !reflect.DeepEqual(ips, []string{"2001:db8::2"}) {
+		t.Errorf("records %v", ips)
+	}
+	if want := []string{"DELETE /domains/example.com/records/1", "DELETE /domains/example.com/records/3"}; !reflect.DeepEqual(writes, want) {
+		t.Errorf("writes %v, want %v", writes, want)
+	}
+}
+
+func TestDigitalOceanError(t *testing.T) {
+	f := newFakeAPI(t)
+	err := newTestProvider(t, f.url, "wrong").UpsertRecord("2001:db8::1")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 401") || !strings.Contains(err.Error(), "Unable to authenticate you") {
+		t.Errorf("bad token: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package dnspod
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const (
+	defaultEndpoint   = "https://dnspod.tencentcloudapi.com"
+	service           = "dnspod"
+	apiVersion        = "2021-03-23"
+	contentType       = "application/json; charset=utf-8"
+	defaultRecordLine = "默认"
+)
+
+// Provider updates records through the DNSPod API of Tencent Cloud (API 3.0)
+type Provider struct {
+	cfg      config.Config
+	keys     config.DNSPodConfig
+	endpoint string
+	client   *http.Client
+}
+
+type recordItem struct {
+	RecordID uint64 `json:"RecordId"`
+	Name     string `json:"Name"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	TTL      int    `json:"TTL"`
+	Line     string `json:"Line"`
+}
+
+type apiError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := defaultEndpoint
+	if cfg.Options.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Options.Endpoint, "/")
+	}
+	p := &Provider{cfg: cfg, keys: *cfg.Options.DNSPod, endpoint: endpoint, client: client}
+	if p.keys.RecordLine == "" {
+		p.keys.RecordLine = defaultRecordLine
+	}
+	return p, nil
+}
+
+// authorization computes the TC3-HMAC-SHA256 Authorization header of a POST
+// to path on host for service
+func authorization(secretID, secretKey, service, host, path string, payload []byte, now time.Time) string {
+	date := now.UTC().Format("2006-01-02")
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		"POST",
+		path,
+		"",
+		"content-type:" + contentType + "\nhost:" + host + "\n",
+		"content-type;host",
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + service + "/tc3_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"TC3-HMAC-SHA256",
+		strconv.FormatInt(now.Unix(), 10),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("TC3"+secretKey), date)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", secretID, scope, signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// call invokes action and decodes the Response object into out. Errors are
+// reported inside the Response object with HTTP 200.
+func (p *Provider) call(action string, params interface{}, out interface{}) error {
+	payload, _ := json.Marshal(params)
+	req, err := http.NewRequest("POST", p.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Version", apiVersion)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("Authorization", authorization(p.keys.SecretID, p.keys.SecretKey, service, req.URL.Host, req.URL.EscapedPath(), payload, now))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Response json.RawMessage `json:"Response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode DNSPod %s response (HTTP %d): %w", action, resp.StatusCode, err)
+	}
+	var status struct {
+		Error *apiError `json:"Error"`
+	}
+	json.Unmarshal(result.Response, &status)
+	if status.Error != nil {
+		return status.Error
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result.Response, out); err != nil {
+		return fmt.Errorf("failed to decode DNSPod %s response: %w", action, err)
+	}
+	return nil
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("DNSPod API error %s: %s", e.Code, e.Message)
+}
+
+// records returns the AAAA records of the configured name on the configured
+// line; records of other lines are left alone
+func (p *Provider) records() ([]recordItem, error) {
+	var result struct {
+		RecordList []recordItem `json:"RecordList"`
+	}
+	err := p.call("DescribeRecordList", map[string]interface{}{
+		"Domain":     p.cfg.Options.Domain.Zone,
+		"Subdomain":  p.cfg.Options.Domain.Record,
+		"RecordType": "AAAA",
+		"RecordLine": p.keys.RecordLine,
+		"Limit":      3000,
+	}, &result)
+	if e, ok := err.(*apiError); ok && e.Code == "ResourceNotFound.NoDataOfRecord" {
+		return nil, nil
+	}
+	return result.RecordList, err
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	records, err := p.records()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, r := range records {
+		ips = append(ips, r.Value)
+	}
+	return ips, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	records, err := p.records()
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+
+	params := map[string]interface{}{
+		"Domain":     p.cfg.Options.Domain.Zone,
+		"SubDomain":  p.cfg.Options.Domain.Record,
+		"RecordType": "AAAA",
+		"RecordLine": p.keys.RecordLine,
+		"Value":      ip,
+		"TTL":        p.cfg.Options.TTL,
+	}
+	if len(records) == 0 {
+		return p.call("CreateRecord", params, nil)
+	}
+
+	// 优先保留已是该地址的记录，删除其余同名 AAAA 记录，使 RRset 只包含 ip
+	keep := 0
+	for i, r := range records {
+		if r.Value == ip {
+			keep = i
+			break
+		}
+	}
+	if existing := records[keep]; existing.Value != ip || existing.TTL != p.cfg.Options.TTL {
+		params["RecordId"] = existing.RecordID
+		if err := p.call("ModifyRecord", params, nil); err != nil {
+			return err
+		}
+	}
+	for i, r := range records {
+		if i == keep {
+			continue
+		}
+		del := map[string]interface{}{"Domain": p.cfg.Options.Domain.Zone, "RecordId": r.RecordID}
+		if err := p.call("DeleteRecord", del, nil); err != nil {
+			return fmt.Errorf("failed to delete extra record %s: %w", r.Value, err)
+		}
+	}
+	return nil
+}
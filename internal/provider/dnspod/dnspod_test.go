@@ -0,0 +1,194 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goddns/internal/config"
+)
+
+// TestAuthorization uses the DescribeInstances example of the Tencent Cloud
+// API 3.0 signature (TC3-HMAC-SHA256) documentation
+func TestAuthorization(t *testing.T) {
+	payload := []byte(`{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`)
+	got := authorization("AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******", "Gu5t9xGARNpq86cd98joQYCN3*******",
+		"cvm", "cvm.tencentcloudapi.com", "/", payload, time.Unix(1551113065, 0))
+
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******/2019-02-25/cvm/tc3_request, " +
+		"SignedHeaders=content-type;host, " +
+		"Signature=2230eefd229f582d8b1b891af7107b91597240707d778ab3738f756258d7652c"
+	if got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+}
+
+// fakeAPI is an in-memory stand-in for the DNSPod API. It checks the
+// Authorization header of every request.
+type fakeAPI struct {
+	mu      sync.Mutex
+	records []recordItem
+	nextID  uint64
+	actions []string
+}
+
+func newFakeAPI(t *testing.T, records ...recordItem) (*fakeAPI, string) {
+	t.Helper()
+	f := &fakeAPI{records: records, nextID: 100}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		reply := func(v interface{}) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"Response": v})
+		}
+		fail := func(code, message string) {
+			reply(map[string]interface{}{"Error": apiError{Code: code, Message: message}, "RequestId": "1"})
+		}
+		payload, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+		if r.Header.Get("Authorization") != authorization("id", "key", service, r.Host, r.URL.Path, payload, time.Unix(ts, 0)) {
+			fail("AuthFailure.SignatureFailure", "The provided credentials could not be validated.")
+			return
+		}
+		var req struct {
+			Domain, Subdomain, SubDomain, RecordType, RecordLine, Value string
+			RecordID                                                    uint64 `json:"RecordId"`
+			TTL                                                         int
+		}
+		json.Unmarshal(payload, &req)
+		action := r.Header.Get("X-TC-Action")
+		if action != "DescribeRecordList" {
+			f.actions = append(f.actions, action)
+		}
+		switch action {
+		case "DescribeRecordList":
+			var found []recordItem
+			for _, rec := range f.records {
+				if rec.Name == req.Subdomain && rec.Type == req.RecordType && rec.Line == req.RecordLine {
+					found = append(found, rec)
+				}
+			}
+			if len(found) == 0 {
+				fail("ResourceNotFound.NoDataOfRecord", "记录列表为空。")
+				return
+			}
+			reply(map[string]interface{}{"RecordList": found})
+		case "CreateRecord":
+			f.records = append(f.records, recordItem{RecordID: f.nextID, Name: req.SubDomain, Type: req.RecordType, Value: req.Value, TTL: req.TTL, Line: req.RecordLine})
+			reply(map[string]interface{}{"RecordId": f.nextID})
+			f.nextID++
+		case "ModifyRecord":
+			for i := range f.records {
+				if f.records[i].RecordID == req.RecordID {
+					f.records[i].Value, f.records[i].TTL = req.Value, req.TTL
+					reply(map[string]interface{}{"RecordId": req.RecordID})
+					return
+				}
+			}
+			fail("ResourceNotFound.NoDataOfRecord", "记录不存在。")
+		case "DeleteRecord":
+			for i := range f.records {
+				if f.records[i].RecordID == req.RecordID {
+					f.records = append(f.records[:i], f.records[i+1:]...)
+					break
+				}
+			}
+			reply(map[string]interface{}{"RequestId": "1"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+// state returns the AAAA contents of home on the default line and the write
+// actions since the last call
+func (f *fakeAPI) state() ([]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ips []string
+	for _, r := range f.records {
+		if r.Type == "AAAA" && r.Name == "home" && r.Line == defaultRecordLine {
+			ips = append(ips, r.Value)
+		}
+	}
+	sort.Strings(ips)
+	actions := f.actions
+	f.actions = nil
+	return ips, actions
+}
+
+func newTestProvider(t *testing.T, endpoint, key string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "dnspod"}
+	cfg.Options.TTL = 600
+	cfg.Options.Endpoint = endpoint
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.DNSPod = &config.DNSPodConfig{SecretID: "id", SecretKey: key}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDNSPodUpsert(t *testing.T) {
+	// 其他线路的记录不受影响
+	f, endpoint := newFakeAPI(t, recordItem{RecordID: 1, Name: "home", Type: "AAAA", Value: "2001:db8::ff", TTL: 600, Line: "电信"})
+	p := newTestProvider(t, endpoint, "key")
+
+	if ips, err := p.GetRecord(); err != nil || len(ips) != 0 {
+		t.Fatalf("GetRecord on empty line = %v, %v", ips, err)
+	}
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if ips, actions := f.state(); !reflect.DeepEqual(ips, []string{"2001:db8::1"}) || !reflect.DeepEqual(actions, []string{"CreateRecord"}) {
+		t.Fatalf("create: records %v, actions %v", ips, actions)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if ips, actions := f.state(); !reflect.DeepEqual(ips, []string{"2001:db8::2"}) || !reflect.DeepEqual(actions, []string{"ModifyRecord"}) {
+		t.Fatalf("update: records %v, actions %v", ips, actions)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, actions := f.state(); len(actions) != 0 {
+		t.Errorf("unchanged record written: %v", actions)
+	}
+}
+
+func TestDNSPodCollapsesRRset(t *testing.T) {
+	f, endpoint := newFakeAPI(t,
+		recordItem{RecordID: 1, Name: "home", Type: "AAAA", Value: "2001:db8::1", TTL: 600, Line: defaultRecordLine},
+		recordItem{RecordID: 2, Name: "home", Type: "AAAA", Value: "2001:db8::2", TTL: 600, Line: defaultRecordLine},
+	)
+	p := newTestProvider(t, endpoint, "key")
+
+	if err := p.UpsertRecord("2001:db8::3"); err != nil {
+		t.Fatal(err)
+	}
+	ips, actions := f.state()
+	if !reflect.DeepEqual(ips, []string{"2001:db8::3"}) || !reflect.DeepEqual(actions, []string{"ModifyRecord", "DeleteRecord"}) {
+		t.Errorf("records %v, actions %v", ips, actions)
+	}
+}
+
+func TestDNSPodError(t *testing.T) {
+	_, endpoint := newFakeAPI(t)
+	err := newTestProvider(t, endpoint, "wrong").UpsertRecord("2001:db8::1")
+	if err == nil || !strings.Contains(err.Error(), "AuthFailure.SignatureFailure") {
+		t.Errorf("bad key: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package duckdns
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const defaultEndpoint = "https://www.duckdns.org/update"
+
+// Provider updates records through the DuckDNS update endpoint
+type Provider struct {
+	cfg      config.Config
+	endpoint string
+	client   *http.Client
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := defaultEndpoint
+	if cfg.Options.Endpoint != "" {
+		endpoint = cfg.Options.Endpoint
+	}
+	return &Provider{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	return nil, errors.New("duckdns cannot read records, use drift_check.method 'dns'")
+}
+
+// UpsertRecord implements provider.Provider. DuckDNS keeps the IPv4 address
+// of the name untouched when only ipv6 is given.
+func (p *Provider) UpsertRecord(ip string) error {
+	query := url.Values{
+		"domains": {p.cfg.Options.Domain.Record},
+		"token":   {p.cfg.Options.APIToken},
+		"ipv6":    {ip},
+		"verbose": {"true"},
+	}
+	resp, err := p.client.Get(p.endpoint + "?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("DuckDNS update failed (HTTP %d)", resp.StatusCode)
+	}
+
+	// verbose 响应：OK/KO，随后为 IPv4、IPv6 与 UPDATED/NOCHANGE
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	switch strings.TrimSpace(lines[0]) {
+	case "OK":
+		return nil
+	case "KO":
+		return errors.New("DuckDNS rejected the update (KO), check the token and the subdomain")
+	}
+	return fmt.Errorf("unexpected DuckDNS response: %q", strings.TrimSpace(string(raw)))
+}
@@ -0,0 +1,90 @@
+package duckdns
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeServer answers the update endpoint with a fixed body and records the
+// queries
+type fakeServer struct {
+	mu      sync.Mutex
+	status  int
+	body    string
+	queries []url.Values
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, string) {
+	t.Helper()
+	f := &fakeServer{status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.queries = append(f.queries, r.URL.Query())
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.body))
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL + "/update"
+}
+
+func (f *fakeServer) reply(status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.body = status, body
+}
+
+func (f *fakeServer) lastQuery() url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queries[len(f.queries)-1]
+}
+
+func newTestProvider(t *testing.T, endpoint string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "duckdns"}
+	cfg.Options.APIToken = "token"
+	cfg.Options.Endpoint = endpoint
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "duckdns.org", "home"
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDuckDNSUpdate(t *testing.T) {
+	f, endpoint := newFakeServer(t)
+	p := newTestProvider(t, endpoint)
+
+	f.reply(http.StatusOK, "OK\n\n2001:db8::1\nUPDATED")
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	// 只传递 ipv6，不修改 IPv4 地址
+	q := f.lastQuery()
+	if q.Get("domains") != "home" || q.Get("token") != "token" || q.Get("ipv6") != "2001:db8::1" || q.Has("ip") {
+		t.Errorf("query %v", q)
+	}
+
+	for _, tc := range []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusOK, "KO", "rejected the update (KO)"},
+		{http.StatusOK, "<html>", `unexpected DuckDNS response: "<html>"`},
+		{http.StatusBadGateway, "", "HTTP 502"},
+	} {
+		f.reply(tc.status, tc.body)
+		if err := p.UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("response %d %q: got %v, want %q", tc.status, tc.body, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package dyndns2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+	"goddns/internal/log"
+)
+
+// serverErrorHold is how long updates are suspended after 911 or dnserr.
+// The protocol asks clients to wait at least 30 minutes.
+const serverErrorHold = 30 * time.Minute
+
+const userAgent = "goddns - dyndns2 client"
+
+// Return codes that block further updates until the configuration changes
+var fatalCodes = map[string]string{
+	"badauth":  "username or password rejected",
+	"badagent": "client blocked by the server",
+	"!donator": "feature not available for this account",
+	"notfqdn":  "hostname is not a fully qualified domain name",
+	"nohost":   "hostname does not exist in this account",
+	"numhost":  "too many hostnames in one request",
+	"abuse":    "hostname blocked for update abuse",
+}
+
+// holdState is persisted so one-shot runs honour a back-off as well
+type holdState struct {
+	Code        string    `json:"code"`
+	Until       time.Time `json:"until"` // 为空表示在配置改变之前一直暂停
+	Fingerprint string    `json:"fingerprint"`
+}
+
+// Provider updates hostnames with the dyndns2 /nic/update protocol
+type Provider struct {
+	cfg       config.DynDNS2Config
+	hostnames []string
+	stateFile string
+	client    *http.Client
+}
+
+// NewProvider constructor. stateFile records a back-off requested by the server.
+func NewProvider(cfg config.Config, stateFile string) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{cfg: *cfg.Options.DynDNS2, stateFile: stateFile, client: client}
+	p.hostnames = p.cfg.Hostnames
+	if len(p.hostnames) == 0 {
+		p.hostnames = []string{cfg.RecordName()}
+	}
+	return p, nil
+}
+
+// GetRecord implements provider.Provider. The protocol has no way to read a
+// record back.
+func (p *Provider) GetRecord() ([]string, error) {
+	return nil, errors.New("dyndns2 cannot read records, use drift_check.method 'dns'")
+}
+
+// fingerprint identifies the settings a permanent hold applies to
+func (p *Provider) fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{p.cfg.Server, p.cfg.Username, p.cfg.Password, strings.Join(p.hostnames, ",")}, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// held returns an error while a back-off requested by the server is active
+func (p *Provider) held() error {
+	data, err := os.ReadFile(p.stateFile)
+	if err != nil {
+		return nil
+	}
+	var st holdState
+	if json.Unmarshal(data, &st) != nil || st.Code == "" {
+		return nil
+	}
+	if st.Until.IsZero() {
+		if st.Fingerprint != p.fingerprint() {
+			// 配置已修改，解除暂停
+			os.Remove(p.stateFile)
+			return nil
+		}
+		return fmt.Errorf("updates suspended after '%s' (%s); fix the dyndns2 settings to resume", st.Code, fatalCodes[st.Code])
+	}
+	if time.Now().Before(st.Until) {
+		return fmt.Errorf("updates suspended after '%s' until %s", st.Code, st.Until.Format(time.RFC3339))
+	}
+	os.Remove(p.stateFile)
+	return nil
+}
+
+func (p *Provider) hold(code string, until time.Time) {
+	data, _ := json.Marshal(holdState{Code: code, Until: until, Fingerprint: p.fingerprint()})
+	if err := os.WriteFile(p.stateFile, data, 0644); err != nil {
+		log.Warning("Failed to write dyndns2 state %s: %v", p.stateFile, err)
+	}
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	if err := p.held(); err != nil {
+		return err
+	}
+
+	param := p.cfg.IPParam
+	if param == "" {
+		param = "myip"
+	}
+	query := url.Values{"hostname": {strings.Join(p.hostnames, ",")}, param: {ip}}
+	endpoint := p.cfg.Server
+	if strings.Contains(endpoint, "?") {
+		endpoint += "&" + query.Encode()
+	} else {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("update request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read update response: %w", err)
+	}
+	return p.interpret(resp.StatusCode, string(body))
+}
+
+// interpret checks the return code of every hostname. The server answers
+// with one line per hostname, in request order.
+func (p *Provider) interpret(status int, body string) error {
+	codes := make([]string, 0, len(p.hostnames))
+	for _, line := range strings.Split(strings.TrimSpace(strings.ReplaceAll(body, "\r", "")), "\n") {
+		if f := strings.Fields(line); len(f) > 0 {
+			codes = append(codes, f[0])
+		}
+	}
+	if len(codes) == 0 {
+		if status == http.StatusUnauthorized {
+			codes = []string{"badauth"}
+		} else {
+			return fmt.Errorf("empty response (HTTP %d)", status)
+		}
+	}
+
+	var errs []error
+	for i, code := range codes {
+		host := strings.Join(p.hostnames, ",")
+		if len(codes) == len(p.hostnames) {
+			host = p.hostnames[i]
+		}
+		switch {
+		case code == "good" || code == "nochg":
+			continue
+		case code == "911" || code == "dnserr":
+			until := time.Now().Add(serverErrorHold)
+			p.hold(code, until)
+			return fmt.Errorf("%s: server error '%s', retrying after %s", host, code, until.Format(time.RFC3339))
+		case fatalCodes[code] != "":
+			p.hold(code, time.Time{})
+			return fmt.Errorf("%s: '%s' (%s), updates suspended until the dyndns2 settings change", host, code, fatalCodes[code])
+		default:
+			errs = append(errs, fmt.Errorf("%s: unexpected response '%s' (HTTP %d)", host, code, status))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package dyndns2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeServer answers /nic/update with a fixed body and records the queries
+type fakeServer struct {
+	mu      sync.Mutex
+	status  int
+	body    string
+	queries []url.Values
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, string) {
+	t.Helper()
+	f := &fakeServer{status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		user, pass, ok := r.BasicAuth()
+		if r.URL.Path != "/nic/update" || r.UserAgent() != userAgent || !ok || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.queries = append(f.queries, r.URL.Query())
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.body))
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL + "/nic/update"
+}
+
+func (f *fakeServer) reply(status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.body = status, body
+}
+
+// requests returns the queries received since the last call
+func (f *fakeServer) requests() []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.queries
+	f.queries = nil
+	return q
+}
+
+func newTestProvider(t *testing.T, dcfg config.DynDNS2Config, stateFile string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "dyndns2"}
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.DynDNS2 = &dcfg
+	p, err := NewProvider(cfg, stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDynDNS2Update(t *testing.T) {
+	f, server := newFakeServer(t)
+	state := filepath.Join(t.TempDir(), "dyndns2.state")
+
+	f.reply(http.StatusOK, "good 2001:db8::1\n")
+	p := newTestProvider(t, config.DynDNS2Config{Server: server, Username: "user", Password: "secret"}, state)
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	q := f.requests()
+	if len(q) != 1 || q[0].Get("hostname") != "home.example.com" || q[0].Get("myip") != "2001:db8::1" {
+		t.Errorf("query %v", q)
+	}
+
+	// 多个主机名逐行检查返回码，自定义地址参数名
+	f.reply(http.StatusOK, "nochg 2001:db8::1\r\ngood 2001:db8::1\r\n")
+	p = newTestProvider(t, config.DynDNS2Config{Server: server + "?system=dyndns", Username: "user", Password: "secret",
+		Hostnames: []string{"a.example.com", "b.example.com"}, IPParam: "myipv6"}, state)
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	q = f.requests()
+	if len(q) != 1 || q[0].Get("hostname") != "a.example.com,b.example.com" || q[0].Get("myipv6") != "2001:db8::1" || q[0].Get("system") != "dyndns" {
+		t.Errorf("query %v", q)
+	}
+
+	f.reply(http.StatusOK, "good 2001:db8::1\nbogus\n")
+	if err := p.UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "b.example.com: unexpected response 'bogus'") {
+		t.Errorf("unexpected code: %v", err)
+	}
+}
+
+func TestDynDNS2ServerErrorHold(t *testing.T) {
+	f, server := newFakeServer(t)
+	state := filepath.Join(t.TempDir(), "dyndns2.state")
+	dcfg := config.DynDNS2Config{Server: server, Username: "user", Password: "secret"}
+
+	f.reply(http.StatusOK, "911")
+	if err := newTestProvider(t, dcfg, state).UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "server error '911'") {
+		t.Fatalf("911: %v", err)
+	}
+	// 暂停状态写入文件，新的进程同样遵守
+	f.reply(http.StatusOK, "good")
+	if err := newTestProvider(t, dcfg, state).UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "suspended after '911' until") {
+		t.Errorf("held update: %v", err)
+	}
+	if n := len(f.requests()); n != 1 {
+		t.Errorf("%d requests sent, want 1", n)
+	}
+}
+
+func TestDynDNS2FatalHold(t *testing.T) {
+	f, server := newFakeServer(t)
+	state := filepath.Join(t.TempDir(), "dyndns2.state")
+	dcfg := config.DynDNS2Config{Server: server, Username: "user", Password: "wrong"}
+
+	// 401 且没有返回码时按 badauth 处理
+	if err := newTestProvider(t, dcfg, state).UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "'badauth'") {
+		t.Fatalf("bad password: %v", err)
+	}
+	if err := newTestProvider(t, dcfg, state).UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "fix the dyndns2 settings to resume") {
+		t.Errorf("held update: %v", err)
+	}
+
+	// 修改配置后解除暂停
+	f.reply(http.StatusOK, "good")
+	dcfg.Password = "secret"
+	if err := newTestProvider(t, dcfg, state).UpsertRecord("2001:db8::1"); err != nil {
+		t.Errorf("after fixing the password: %v", err)
+	}
+	if n := len(f.requests()); n != 1 {
+		t.Errorf("%d requests accepted, want 1", n)
+	}
+}
+
+func TestDynDNS2GetRecord(t *testing.T) {
+	p := newTestProvider(t, config.DynDNS2Config{Server: "http://127.0.0.1:1/nic/update"}, filepath.Join(t.TempDir(), "dyndns2.state"))
+	if _, err := p.GetRecord(); err == nil || !strings.Contains(err.Error(), "drift_check.method 'dns'") {
+		t.Errorf("GetRecord: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/log"
+)
+
+// ProtocolVersion is sent with every request so plugins can reject versions they do not know
+const ProtocolVersion = 1
+
+// Actions of the protocol
+const (
+	ActionGetRecord    = "get_record"
+	ActionUpsertRecord = "upsert_record"
+	ActionDeleteRecord = "delete_record"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Request is written as one JSON document to the plugin's stdin
+type Request struct {
+	Version int             `json:"version"`
+	Action  string          `json:"action"`
+	Zone    string          `json:"zone"`
+	Record  string          `json:"record"`
+	Name    string          `json:"name"` // record + "." + zone
+	Type    string          `json:"type"`
+	TTL     int             `json:"ttl"`
+	IP      string          `json:"ip,omitempty"` // upsert_record only
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// Response is read as one JSON document from the plugin's stdout
+type Response struct {
+	OK        bool     `json:"ok"`
+	Addresses []string `json:"addresses,omitempty"` // get_record only
+	Error     string   `json:"error,omitempty"`
+}
+
+// Provider delegates record operations to an external program
+type Provider struct {
+	cfg     config.Config
+	plugin  config.ExecConfig
+	timeout time.Duration
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	p := &Provider{cfg: cfg, plugin: *cfg.Options.Exec, timeout: defaultTimeout}
+	if p.plugin.Timeout > 0 {
+		p.timeout = time.Duration(p.plugin.Timeout) * time.Second
+	}
+	if _, err := exec.LookPath(p.plugin.Command); err != nil {
+		return nil, fmt.Errorf("exec provider: %w", err)
+	}
+	return p, nil
+}
+
+// call runs the plugin once for action. Lines the plugin writes to stderr are logged.
+func (p *Provider) call(action, ip string) (*Response, error) {
+	req := Request{
+		Version: ProtocolVersion,
+		Action:  action,
+		Zone:    p.cfg.Options.Domain.Zone,
+		Record:  p.cfg.Options.Domain.Record,
+		Name:    p.cfg.RecordName(),
+		Type:    "AAAA",
+		TTL:     p.cfg.Options.TTL,
+		IP:      ip,
+		Options: p.plugin.Options,
+	}
+	input, _ := json.Marshal(req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.plugin.Command, p.plugin.Args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// 超时后子进程仍持有输出管道时不无限等待
+	cmd.WaitDelay = time.Second
+
+	runErr := cmd.Run()
+	var lastLine string
+	for _, line := range strings.Split(strings.TrimRight(stderr.String(), "\n"), "\n") {
+		if line != "" {
+			log.Info("[exec %s] %s", action, line)
+			lastLine = line
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("exec provider %s timed out after %s", action, p.timeout)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("exec provider %s failed: %v: %s", action, runErr, lastLine)
+		}
+		return nil, fmt.Errorf("exec provider %s returned an invalid response: %w", action, err)
+	}
+	if !resp.OK {
+		if resp.Error == "" {
+			resp.Error = "no error message"
+		}
+		return nil, fmt.Errorf("exec provider %s failed: %s", action, resp.Error)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("exec provider %s failed: %v", action, runErr)
+	}
+	return &resp, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	resp, err := p.call(ActionGetRecord, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Addresses, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	_, err := p.call(ActionUpsertRecord, ip)
+	return err
+}
+
+// DeleteRecord removes all AAAA records of the configured name
+func (p *Provider) DeleteRecord() error {
+	_, err := p.call(ActionDeleteRecord, "")
+	return err
+}
@@ -0,0 +1,124 @@
+package exec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// stubPlugin writes the request to request.json in dir and answers with the
+// content of response.json
+const stubPlugin = `#!/bin/sh
+cat > "$1/request.json"
+echo "handled by stub" >&2
+cat "$1/response.json"
+`
+
+func newStub(t *testing.T, response string) (*Provider, string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "plugin.sh")
+	if err := os.WriteFile(script, []byte(stubPlugin), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "response.json"), []byte(response), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Config{Provider: "exec"}
+	cfg.Options.TTL = 300
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.Exec = &config.ExecConfig{Command: script, Args: []string{dir}, Options: json.RawMessage(`{"endpoint":"https://dns.corp.example"}`)}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, dir
+}
+
+func readRequest(t *testing.T, dir string) Request {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("plugin got invalid JSON %q: %v", data, err)
+	}
+	return req
+}
+
+func TestExecActions(t *testing.T) {
+	p, dir := newStub(t, `{"ok": true, "addresses": ["2001:db8::1", "2001:db8::2"]}`)
+
+	addrs, err := p.GetRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2001:db8::1", "2001:db8::2"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("GetRecord = %v, want %v", addrs, want)
+	}
+	req := readRequest(t, dir)
+	if req.Version != ProtocolVersion || req.Action != ActionGetRecord || req.Name != "home.example.com" ||
+		req.Zone != "example.com" || req.Record != "home" || req.Type != "AAAA" || req.TTL != 300 || req.IP != "" {
+		t.Errorf("unexpected get_record request %+v", req)
+	}
+	if string(req.Options) != `{"endpoint":"https://dns.corp.example"}` {
+		t.Errorf("options not passed through: %s", req.Options)
+	}
+
+	if err := p.UpsertRecord("2001:db8::3"); err != nil {
+		t.Fatal(err)
+	}
+	if req := readRequest(t, dir); req.Action != ActionUpsertRecord || req.IP != "2001:db8::3" {
+		t.Errorf("unexpected upsert_record request %+v", req)
+	}
+
+	if err := p.DeleteRecord(); err != nil {
+		t.Fatal(err)
+	}
+	if req := readRequest(t, dir); req.Action != ActionDeleteRecord || req.IP != "" {
+		t.Errorf("unexpected delete_record request %+v", req)
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{`{"ok": false, "error": "backend said no"}`, "backend said no"},
+		{`{"ok": false}`, "no error message"},
+		{`not json`, "invalid response"},
+	}
+	for _, tt := range tests {
+		p, _ := newStub(t, tt.response)
+		err := p.UpsertRecord("2001:db8::1")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("response %s: error %v, want it to contain %q", tt.response, err, tt.want)
+		}
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "slow.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 5\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Config{Provider: "exec"}
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.Exec = &config.ExecConfig{Command: script, Timeout: 1}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("want timeout error, got %v", err)
+	}
+}
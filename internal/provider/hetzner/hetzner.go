@@ -0,0 +1,210 @@
+package hetzner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"goddns/internal/config"
+	"goddns/internal/httpclient"
+)
+
+const (
+	defaultEndpoint = "https://dns.hetzner.com/api/v1"
+	perPage         = 100
+)
+
+// Provider updates records through the Hetzner DNS API
+type Provider struct {
+	cfg      config.Config
+	endpoint string
+	zoneID   string // 缓存查询到的 Zone ID
+	client   *http.Client
+}
+
+type record struct {
+	ID     string `json:"id,omitempty"`
+	ZoneID string `json:"zone_id"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	TTL    int    `json:"ttl"`
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := defaultEndpoint
+	if cfg.Options.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Options.Endpoint, "/")
+	}
+	return &Provider{
+		cfg:      cfg,
+		endpoint: endpoint,
+		zoneID:   cfg.Options.ZoneID,
+		client:   client,
+	}, nil
+}
+
+// request sends an API request and decodes the JSON reply into out when given
+func (p *Provider) request(method, endpoint string, data interface{}, out interface{}) error {
+	var body io.Reader
+	if data != nil {
+		jsonBody, _ := json.Marshal(data)
+		body = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Auth-API-Token", p.cfg.Options.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr struct {
+			Message string `json:"message"`
+			Error   struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(raw, &apiErr)
+		msg := apiErr.Error.Message
+		if msg == "" {
+			msg = apiErr.Message
+		}
+		if msg == "" {
+			msg = strings.TrimSpace(string(raw))
+		}
+		return fmt.Errorf("Hetzner DNS API %s failed (HTTP %d): %s", method, resp.StatusCode, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Hetzner DNS API response: %w", err)
+	}
+	return nil
+}
+
+// zone returns the configured Zone ID, looking it up by name once when not set
+func (p *Provider) zone() (string, error) {
+	if p.zoneID != "" {
+		return p.zoneID, nil
+	}
+	var result struct {
+		Zones []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"zones"`
+	}
+	query := url.Values{"name": {p.cfg.Options.Domain.Zone}}
+	if err := p.request("GET", p.endpoint+"/zones?"+query.Encode(), nil, &result); err != nil {
+		return "", err
+	}
+	for _, z := range result.Zones {
+		if strings.EqualFold(z.Name, p.cfg.Options.Domain.Zone) {
+			p.zoneID = z.ID
+			return z.ID, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find zone %s", p.cfg.Options.Domain.Zone)
+}
+
+// records returns the AAAA records of the configured name, following pagination
+func (p *Provider) records(zoneID string) ([]record, error) {
+	var all []record
+	for page := 1; ; page++ {
+		var result struct {
+			Records []record `json:"records"`
+			Meta    struct {
+				Pagination struct {
+					LastPage int `json:"last_page"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		query := url.Values{"zone_id": {zoneID}, "page": {strconv.Itoa(page)}, "per_page": {strconv.Itoa(perPage)}}
+		if err := p.request("GET", p.endpoint+"/records?"+query.Encode(), nil, &result); err != nil {
+			return nil, err
+		}
+		for _, r := range result.Records {
+			if r.Type == "AAAA" && strings.EqualFold(r.Name, p.cfg.Options.Domain.Record) {
+				all = append(all, r)
+			}
+		}
+		if page >= result.Meta.Pagination.LastPage || len(result.Records) == 0 {
+			return all, nil
+		}
+	}
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	zoneID, err := p.zone()
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.records(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, r := range records {
+		ips = append(ips, r.Value)
+	}
+	return ips, nil
+}
+
+// UpsertRecord implements provider.Provider
+func (p *Provider) UpsertRecord(ip string) error {
+	zoneID, err := p.zone()
+	if err != nil {
+		return err
+	}
+	records, err := p.records(zoneID)
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+
+	rec := record{ZoneID: zoneID, Type: "AAAA", Name: p.cfg.Options.Domain.Record, Value: ip, TTL: p.cfg.Options.TTL}
+	if len(records) == 0 {
+		return p.request("POST", p.endpoint+"/records", rec, nil)
+	}
+
+	// 优先保留已是该地址的记录，删除其余同名 AAAA 记录，使 RRset 只包含 ip
+	keep := 0
+	for i, r := range records {
+		if r.Value == ip {
+			keep = i
+			break
+		}
+	}
+	if existing := records[keep]; existing.Value != ip || existing.TTL != p.cfg.Options.TTL {
+		if err := p.request("PUT", p.endpoint+"/records/"+url.PathEscape(existing.ID), rec, nil); err != nil {
+			return err
+		}
+	}
+	for i, r := range records {
+		if i == keep {
+			continue
+		}
+		if err := p.request("DELETE", p.endpoint+"/records/"+url.PathEscape(r.ID), nil, nil); err != nil {
+			return fmt.Errorf("failed to delete extra record %s: %w", r.Value, err)
+		}
+	}
+	return nil
+}
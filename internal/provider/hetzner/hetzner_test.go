@@ -0,0 +1,207 @@
+package hetzner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeAPI is an in-memory stand-in for the zones and records endpoints. It
+// returns one record per page to exercise pagination.
+type fakeAPI struct {
+	mu      sync.Mutex
+	url     string
+	records []record
+	nextID  int
+	calls   []string
+}
+
+func newFakeAPI(t *testing.T, records ...record) *fakeAPI {
+	t.Helper()
+	f := &fakeAPI{records: records, nextID: 100}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /zones", func(w http.ResponseWriter, r *http.Request) {
+		zones := []map[string]string{}
+		if r.URL.Query().Get("name") == "example.com" {
+			zones = append(zones, map[string]string{"id": "zone1", "name": "example.com"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"zones": zones})
+	})
+	mux.HandleFunc("GET /records", func(w http.ResponseWriter, r *http.Request) {
+		var found []record
+		for _, rec := range f.records {
+			if rec.ZoneID == r.URL.Query().Get("zone_id") {
+				found = append(found, rec)
+			}
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		reply := map[string]interface{}{"records": []record{}}
+		if page <= len(found) {
+			reply["records"] = found[page-1 : page]
+		}
+		reply["meta"] = map[string]interface{}{"pagination": map[string]int{"page": page, "last_page": max(len(found), 1)}}
+		json.NewEncoder(w).Encode(reply)
+	})
+	mux.HandleFunc("POST /records", func(w http.ResponseWriter, r *http.Request) {
+		var rec record
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = fmt.Sprint(f.nextID)
+		f.nextID++
+		f.records = append(f.records, rec)
+		json.NewEncoder(w).Encode(map[string]interface{}{"record": rec})
+	})
+	mux.HandleFunc("PUT /records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for i := range f.records {
+			if f.records[i].ID == r.PathValue("id") {
+				json.NewDecoder(r.Body).Decode(&f.records[i])
+				f.records[i].ID = r.PathValue("id")
+				json.NewEncoder(w).Encode(map[string]interface{}{"record": f.records[i]})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"message": "record not found"}})
+	})
+	mux.HandleFunc("DELETE /records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for i := range f.records {
+			if f.records[i].ID == r.PathValue("id") {
+				f.records = append(f.records[:i], f.records[i+1:]...)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Header.Get("Auth-API-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid authentication credentials"})
+			return
+		}
+		f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	return f
+}
+
+// state returns the AAAA contents of home and the write requests since the
+// last call
+func (f *fakeAPI) state() ([]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ips []string
+	for _, r := range f.records {
+		if r.Type == "AAAA" && r.Name == "home" {
+			ips = append(ips, r.Value)
+		}
+	}
+	sort.Strings(ips)
+	var writes []string
+	for _, c := range f.calls {
+		if !strings.HasPrefix(c, "GET ") {
+			writes = append(writes, c)
+		}
+	}
+	f.calls = nil
+	return ips, writes
+}
+
+func (f *fakeAPI) zoneLookups() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == "GET /zones" {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestProvider(t *testing.T, endpoint, token string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "hetzner"}
+	cfg.Options.APIToken = token
+	cfg.Options.TTL = 300
+	cfg.Options.Endpoint = endpoint
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestHetznerUpsert(t *testing.T) {
+	f := newFakeAPI(t, record{ID: "1", ZoneID: "zone1", Type: "A", Name: "home", Value: "192.0.2.1", TTL: 300})
+	p := newTestProvider(t, f.url, "token")
+
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.zoneLookups(); n != 1 {
+		t.Errorf("zone looked up %d times, want once", n)
+	}
+	ips, writes := f.state()
+	if !reflect.DeepEqual(ips, []string{"2001:db8::2"}) || !reflect.DeepEqual(writes, []string{"POST /records", "PUT /records/100"}) {
+		t.Fatalf("records %v, writes %v", ips, writes)
+	}
+
+	if err := p.UpsertRecord("2001:db8::2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, writes := f.state(); len(writes) != 0 {
+		t.Errorf("unchanged record written: %v", writes)
+	}
+}
+
+func TestHetznerCollapsesRRset(t *testing.T) {
+	f := newFakeAPI(t,
+		record{ID: "1", ZoneID: "zone1", Type: "AAAA", Name: "home", Value: "2001:db8::1", TTL: 300},
+		record{ID: "2", ZoneID: "zone1", Type: "AAAA", Name: "other", Value: "2001:db8::9", TTL: 300},
+		record{ID: "3", ZoneID: "zone1", Type: "AAAA", Name: "home", Value: "2001:db8::3", TTL: 300},
+	)
+	p := newTestProvider(t, f.url, "token")
+
+	// 多页中的全部记录都能读到，且只返回该名称的记录
+	ips, err := p.GetRecord()
+	if sort.Strings(ips); err != nil || !reflect.DeepEqual(ips, []string{"2001:db8::1", "2001:db8::3"}) {
+		t.Fatalf("GetRecord = %v, %v", ips, err)
+	}
+
+	// 没有记录是新地址时更新第一条并删除其余记录
+	if err := p.UpsertRecord("2001:db8::4"); err != nil {
+		t.Fatal(err)
+	}
+	ips, writes := f.state()
+	if !reflect.DeepEqual(ips, []string{"2001:db8::4"}) {
+		t.Errorf("records %v", ips)
+	}
+	if want := []string{"PUT /records/1", "DELETE /records/3"}; !reflect.DeepEqual(writes, want) {
+		t.Errorf("writes %v, want %v", writes, want)
+	}
+}
+
+func TestHetznerError(t *testing.T) {
+	f := newFakeAPI(t)
+	err := newTestProvider(t, f.url, "wrong").UpsertRecord("2001:db8::1")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 401") || !strings.Contains(err.Error(), "Invalid authentication credentials") {
+		t.Errorf("bad token: %v", err)
+	}
+}
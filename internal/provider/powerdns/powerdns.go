@@ -0,0 +1,164 @@
+package powerdns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"goddns/internal/config"
+	"goddns/internal/dnsclient"
+	"goddns/internal/httpclient"
+)
+
+const defaultServerID = "localhost"
+
+// Provider updates records through the PowerDNS Authoritative HTTP API
+type Provider struct {
+	cfg    config.Config
+	zone   string // API 中的 zone id，即带结尾点的区域名
+	fqdn   string
+	client *http.Client
+}
+
+type record struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type rrset struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	TTL        int      `json:"ttl,omitempty"`
+	ChangeType string   `json:"changetype,omitempty"`
+	Records    []record `json:"records"`
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	zone := cfg.Options.ZoneID
+	if zone == "" {
+		zone = dnsclient.Fqdn(cfg.Options.Domain.Zone)
+	}
+	return &Provider{
+		cfg:    cfg,
+		zone:   zone,
+		fqdn:   dnsclient.Fqdn(cfg.RecordName()),
+		client: client,
+	}, nil
+}
+
+func (p *Provider) zoneURL() string {
+	serverID := p.cfg.Options.PowerDNS.ServerID
+	if serverID == "" {
+		serverID = defaultServerID
+	}
+	return fmt.Sprintf("%s/api/v1/servers/%s/zones/%s",
+		strings.TrimRight(p.cfg.Options.PowerDNS.URL, "/"), url.PathEscape(serverID), url.PathEscape(p.zone))
+}
+
+// request sends an API request and decodes the JSON reply into out when given
+func (p *Provider) request(method, endpoint string, data interface{}, out interface{}) error {
+	var body io.Reader
+	if data != nil {
+		jsonBody, _ := json.Marshal(data)
+		body = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", p.cfg.Options.APIToken)
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(raw, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(raw))
+		}
+		return fmt.Errorf("PowerDNS API %s failed (HTTP %d): %s", method, resp.StatusCode, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode PowerDNS API response: %w", err)
+	}
+	return nil
+}
+
+// findRRset returns the AAAA RRset of the record, or nil if it does not exist
+func (p *Provider) findRRset() (*rrset, error) {
+	var zone struct {
+		RRsets []rrset `json:"rrsets"`
+	}
+	// 较新版本支持按名称与类型过滤，旧版本会忽略这些参数并返回整个区域
+	query := url.Values{"rrset_name": {p.fqdn}, "rrset_type": {"AAAA"}}
+	if err := p.request("GET", p.zoneURL()+"?"+query.Encode(), nil, &zone); err != nil {
+		return nil, err
+	}
+	for i, rr := range zone.RRsets {
+		if rr.Type == "AAAA" && strings.EqualFold(rr.Name, p.fqdn) {
+			return &zone.RRsets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	rr, err := p.findRRset()
+	if err != nil || rr == nil {
+		return nil, err
+	}
+	var ips []string
+	for _, r := range rr.Records {
+		if !r.Disabled {
+			ips = append(ips, r.Content)
+		}
+	}
+	return ips, nil
+}
+
+// UpsertRecord implements provider.Provider. The RRset is replaced as a whole
+// and left untouched when it already holds exactly ip with the configured TTL.
+func (p *Provider) UpsertRecord(ip string) error {
+	existing, err := p.findRRset()
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+	if existing != nil && existing.TTL == p.cfg.Options.TTL &&
+		len(existing.Records) == 1 && existing.Records[0].Content == ip && !existing.Records[0].Disabled {
+		return nil
+	}
+
+	change := struct {
+		RRsets []rrset `json:"rrsets"`
+	}{[]rrset{{
+		Name:       p.fqdn,
+		Type:       "AAAA",
+		TTL:        p.cfg.Options.TTL,
+		ChangeType: "REPLACE",
+		Records:    []record{{Content: ip}},
+	}}}
+	return p.request("PATCH", p.zoneURL(), change, nil)
+}
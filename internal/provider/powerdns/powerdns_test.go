@@ -0,0 +1,144 @@
+package powerdns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeServer stands in for the zone endpoint of the PowerDNS API
+type fakeServer struct {
+	mu      sync.Mutex
+	rrsets  []rrset
+	patches []rrset
+	filter  bool // 是否支持 rrset_name/rrset_type 过滤
+}
+
+func newFakeServer(t *testing.T, filter bool, rrsets ...rrset) (*fakeServer, string) {
+	t.Helper()
+	f := &fakeServer{rrsets: rrsets, filter: filter}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Unauthorized"))
+			return
+		}
+		if r.URL.Path != "/api/v1/servers/localhost/zones/example.com." {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Could not find domain '" + r.URL.Path + "'"})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			var out []rrset
+			for _, rr := range f.rrsets {
+				q := r.URL.Query()
+				if !f.filter || (rr.Name == q.Get("rrset_name") && rr.Type == q.Get("rrset_type")) {
+					out = append(out, rr)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"name": "example.com.", "rrsets": out})
+		case "PATCH":
+			var change struct {
+				RRsets []rrset `json:"rrsets"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&change); err != nil || r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid body"})
+				return
+			}
+			f.patches = append(f.patches, change.RRsets...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+func (f *fakeServer) received() []rrset {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]rrset(nil), f.patches...)
+}
+
+func newTestProvider(t *testing.T, url, key string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "powerdns"}
+	cfg.Options.APIToken = key
+	cfg.Options.TTL = 300
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.PowerDNS = &config.PowerDNSConfig{URL: url + "/"}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPowerDNSUpsert(t *testing.T) {
+	for _, filter := range []bool{true, false} {
+		f, url := newFakeServer(t, filter,
+			rrset{Name: "www.example.com.", Type: "AAAA", TTL: 300, Records: []record{{Content: "2001:db8::80"}}},
+			rrset{Name: "home.example.com.", Type: "AAAA", TTL: 300, Records: []record{{Content: "2001:db8::1"}, {Content: "2001:db8::2", Disabled: true}}},
+		)
+		p := newTestProvider(t, url, "secret")
+
+		ips, err := p.GetRecord()
+		if err != nil || !reflect.DeepEqual(ips, []string{"2001:db8::1"}) {
+			t.Fatalf("filter=%v: GetRecord = %v, %v", filter, ips, err)
+		}
+
+		// 禁用的记录也要被替换掉
+		if err := p.UpsertRecord("2001:db8::1"); err != nil {
+			t.Fatal(err)
+		}
+		want := []rrset{{Name: "home.example.com.", Type: "AAAA", TTL: 300, ChangeType: "REPLACE", Records: []record{{Content: "2001:db8::1"}}}}
+		if got := f.received(); !reflect.DeepEqual(got, want) {
+			t.Errorf("filter=%v: PATCH %+v, want %+v", filter, got, want)
+		}
+	}
+}
+
+func TestPowerDNSUnchanged(t *testing.T) {
+	f, url := newFakeServer(t, true,
+		rrset{Name: "home.example.com.", Type: "AAAA", TTL: 300, Records: []record{{Content: "2001:db8::1"}}},
+	)
+	p := newTestProvider(t, url, "secret")
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.received(); len(got) != 0 {
+		t.Errorf("unchanged RRset patched: %+v", got)
+	}
+
+	// TTL 不同时重新写入
+	p.cfg.Options.TTL = 60
+	if err := p.UpsertRecord("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.received(); len(got) != 1 || got[0].TTL != 60 {
+		t.Errorf("TTL change: PATCH %+v", got)
+	}
+}
+
+func TestPowerDNSErrors(t *testing.T) {
+	_, url := newFakeServer(t, true)
+	if err := newTestProvider(t, url, "wrong").UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "HTTP 401): Unauthorized") {
+		t.Errorf("bad key: %v", err)
+	}
+	p := newTestProvider(t, url, "secret")
+	p.zone = "example.org."
+	if err := p.UpsertRecord("2001:db8::1"); err == nil || !strings.Contains(err.Error(), "Could not find domain") {
+		t.Errorf("unknown zone: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"fmt"
+
+	"goddns/internal/config"
+	"goddns/internal/provider/aliyun"
+	"goddns/internal/provider/cloudflare"
+	"goddns/internal/provider/desec"
+	"goddns/internal/provider/digitalocean"
+	"goddns/internal/provider/dnspod"
+	"goddns/internal/provider/duckdns"
+	"goddns/internal/provider/dyndns2"
+	"goddns/internal/provider/exec"
+	"goddns/internal/provider/hetzner"
+	"goddns/internal/provider/powerdns"
+	"goddns/internal/provider/rfc2136"
+	"goddns/internal/provider/route53"
+)
+
+// Provider publishes the AAAA record configured in provider_options.domain
+type Provider interface {
+	// GetRecord returns the addresses the record points at, nil if it does not exist
+	GetRecord() ([]string, error)
+	// UpsertRecord creates the record or replaces its addresses with ip
+	UpsertRecord(ip string) error
+}
+
+// Deleter is implemented by providers that can remove the record. It is used
+// when a record or mirror is dropped from the config on reload.
+type Deleter interface {
+	// DeleteRecord removes all AAAA records of the configured name
+	DeleteRecord() error
+}
+
+// New returns the provider selected by cfg.Provider. Providers that keep state
+// between runs store it next to the other work files of configFile.
+func New(cfg config.Config, configFile string) (Provider, error) {
+	return NewTarget(cfg, configFile, "")
+}
+
+// NewTarget is New for one of several providers or records managed together
+// (mirrors, hosts). statePrefix keeps their state files apart.
+func NewTarget(cfg config.Config, configFile, statePrefix string) (Provider, error) {
+	switch cfg.Provider {
+	case "cloudflare":
+		return cloudflare.NewProvider(cfg), nil
+	case "rfc2136":
+		p, err := rfc2136.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "powerdns":
+		p, err := powerdns.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "route53":
+		p, err := route53.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "dyndns2":
+		p, err := dyndns2.NewProvider(cfg, config.GetWorkFilePath(configFile, cfg.WorkDir, statePrefix+"dyndns2.state"))
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "digitalocean":
+		p, err := digitalocean.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "hetzner":
+		p, err := hetzner.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "aliyun":
+		p, err := aliyun.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "dnspod":
+		p, err := dnspod.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "desec":
+		p, err := desec.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "duckdns":
+		p, err := duckdns.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "exec":
+		p, err := exec.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unsupported provider '%s'", cfg.Provider)
+}
@@ -0,0 +1,182 @@
+package rfc2136
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"goddns/internal/config"
+	"goddns/internal/dnsclient"
+)
+
+const (
+	opUpdate      dnsmessage.OpCode = 5
+	classNONE     dnsmessage.Class  = 254
+	updateTimeout                   = 30 * time.Second
+)
+
+// RFC 2136 update response codes
+var rcodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN (prerequisite: name is not in use)",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+	6:                              "YXDOMAIN (prerequisite: name is in use)",
+	7:                              "YXRRSET (prerequisite: RRset exists)",
+	8:                              "NXRRSET (prerequisite: RRset does not exist)",
+	9:                              "NOTAUTH (server is not authoritative or the key was rejected)",
+	10:                             "NOTZONE (record is outside the zone)",
+}
+
+// Provider sends RFC 2136 dynamic updates to the primary server of the zone
+type Provider struct {
+	cfg  config.RFC2136Config
+	zone string
+	fqdn string
+	ttl  uint32
+	key  *tsigKey
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	if cfg.Options.RFC2136 == nil {
+		return nil, errors.New("provider_options.rfc2136 is not configured")
+	}
+	p := &Provider{
+		cfg:  *cfg.Options.RFC2136,
+		zone: dnsclient.Fqdn(cfg.Options.Domain.Zone),
+		fqdn: dnsclient.Fqdn(cfg.RecordName()),
+		ttl:  uint32(cfg.Options.TTL),
+	}
+	if p.cfg.KeyName != "" {
+		secret, err := base64.StdEncoding.DecodeString(p.cfg.KeySecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TSIG secret: %w", err)
+		}
+		p.key, err = newTSIGKey(p.cfg.KeyName, p.cfg.KeyAlgorithm, secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// GetRecord implements provider.Provider by asking the primary server directly
+func (p *Provider) GetRecord() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+	return dnsclient.LookupAAAA(ctx, p.cfg.Server, p.fqdn, false)
+}
+
+// UpsertRecord implements provider.Provider. The AAAA RRset is deleted and
+// the new address added in the same update, which the server applies atomically.
+func (p *Provider) UpsertRecord(ip string) error {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.To4() != nil {
+		return fmt.Errorf("'%s' is not an IPv6 address", ip)
+	}
+	msg, err := p.buildUpdate(addr)
+	if err != nil {
+		return fmt.Errorf("failed to build update message: %w", err)
+	}
+	var requestMAC []byte
+	if p.key != nil {
+		msg, requestMAC = p.key.sign(msg, time.Now())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+	server := dnsclient.ServerAddr(p.cfg.Server)
+	reply, err := dnsclient.Exchange(ctx, server, msg, p.cfg.Transport == "tcp")
+	if err != nil {
+		return fmt.Errorf("update request to %s failed: %w", server, err)
+	}
+	if len(reply) < 12 {
+		return fmt.Errorf("short reply from %s", server)
+	}
+
+	rcode := dnsmessage.RCode(binary.BigEndian.Uint16(reply[2:4]) & 0x0f)
+	if p.key != nil {
+		// 服务器不认识密钥时可能返回未签名的错误响应，此时优先报告 rcode
+		if err := p.key.verify(reply, requestMAC, time.Now()); err != nil && !(errors.Is(err, errUnsigned) && rcode != dnsmessage.RCodeSuccess) {
+			return fmt.Errorf("invalid reply from %s: %w", server, err)
+		}
+	}
+	if rcode != dnsmessage.RCodeSuccess {
+		name, ok := rcodeNames[rcode]
+		if !ok {
+			name = fmt.Sprintf("rcode %d", rcode)
+		}
+		return fmt.Errorf("%s rejected the update: %s", server, name)
+	}
+	return nil
+}
+
+// buildUpdate packs an UPDATE message. The zone, prerequisite and update
+// sections reuse the question, answer and authority sections of a query.
+func (p *Provider) buildUpdate(ip net.IP) ([]byte, error) {
+	zone, err := dnsmessage.NewName(p.zone)
+	if err != nil {
+		return nil, err
+	}
+	name, err := dnsmessage.NewName(p.fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: dnsclient.NewID(), OpCode: opUpdate})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if p.cfg.Prerequisite != "" {
+		typ, class := prerequisite(p.cfg.Prerequisite)
+		hdr := dnsmessage.ResourceHeader{Name: name, Class: class}
+		if err := b.UnknownResource(hdr, dnsmessage.UnknownResource{Type: typ}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.StartAuthorities(); err != nil {
+		return nil, err
+	}
+	// class ANY + 空 RDATA：删除该名称下的整个 AAAA RRset
+	del := dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassANY}
+	if err := b.UnknownResource(del, dnsmessage.UnknownResource{Type: dnsmessage.TypeAAAA}); err != nil {
+		return nil, err
+	}
+	var aaaa dnsmessage.AAAAResource
+	copy(aaaa.AAAA[:], ip.To16())
+	add := dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: p.ttl}
+	if err := b.AAAAResource(add, aaaa); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// prerequisite maps the configured prerequisite to the RR type and class of
+// RFC 2136 section 2.4
+func prerequisite(kind string) (dnsmessage.Type, dnsmessage.Class) {
+	switch kind {
+	case "name_in_use":
+		return dnsmessage.TypeALL, dnsmessage.ClassANY
+	case "name_not_in_use":
+		return dnsmessage.TypeALL, classNONE
+	case "rrset_exists":
+		return dnsmessage.TypeAAAA, dnsmessage.ClassANY
+	}
+	return dnsmessage.TypeAAAA, classNONE
+}
@@ -0,0 +1,330 @@
+package rfc2136
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"goddns/internal/config"
+)
+
+// rr is a resource record of a received UPDATE
+type rr struct {
+	name  string
+	typ   dnsmessage.Type
+	class dnsmessage.Class
+	ttl   uint32
+	data  []byte
+}
+
+// update is what the fake server saw in a request
+type update struct {
+	transport string
+	opcode    dnsmessage.OpCode
+	zone      dnsmessage.Question
+	prereqs   []rr
+	updates   []rr
+	signed    bool
+	tsigErr   error // 服务器端校验请求签名的结果
+}
+
+// fakeServer stands in for the primary server of the zone. It listens on the
+// same port over UDP and TCP, records each UPDATE, checks its TSIG signature
+// and answers with the configured rcode.
+type fakeServer struct {
+	addr string
+	key  *tsigKey
+
+	mu       sync.Mutex
+	rcode    dnsmessage.RCode
+	unsigned bool // 不签名响应，模拟不认识密钥的服务器
+	badSig   bool // 以错误的 MAC 签名响应
+	updates  []update
+}
+
+func newFakeServer(t *testing.T, key *tsigKey) *fakeServer {
+	t.Helper()
+	var pc net.PacketConn
+	var ln net.Listener
+	for i := 0; ln == nil; i++ {
+		var err error
+		if pc, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		if ln, err = net.Listen("tcp", pc.LocalAddr().String()); err != nil {
+			pc.Close()
+			if i == 10 {
+				t.Fatal(err)
+			}
+		}
+	}
+	s := &fakeServer{addr: pc.LocalAddr().String(), key: key}
+	t.Cleanup(func() { pc.Close(); ln.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(s.handle("udp", append([]byte(nil), buf[:n]...)), from)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, msg); err != nil {
+					return
+				}
+				reply := s.handle("tcp", msg)
+				out := binary.BigEndian.AppendUint16(nil, uint16(len(reply)))
+				conn.Write(append(out, reply...))
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) set(rcode dnsmessage.RCode, unsigned, badSig bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rcode, s.unsigned, s.badSig = rcode, unsigned, badSig
+}
+
+func (s *fakeServer) received() []update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]update(nil), s.updates...)
+}
+
+func (s *fakeServer) handle(transport string, msg []byte) []byte {
+	u, requestMAC := parseUpdate(msg, s.key)
+	u.transport = transport
+	s.mu.Lock()
+	s.updates = append(s.updates, u)
+	rcode, unsigned, badSig := s.rcode, s.unsigned, s.badSig
+	s.mu.Unlock()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: binary.BigEndian.Uint16(msg), Response: true, OpCode: opUpdate, RCode: rcode})
+	b.StartQuestions()
+	b.Question(u.zone)
+	reply, _ := b.Finish()
+	if unsigned || requestMAC == nil {
+		return reply
+	}
+	if badSig {
+		requestMAC = append([]byte{requestMAC[0] ^ 0xff}, requestMAC[1:]...)
+	}
+	return signReply(s.key, reply, requestMAC, time.Now())
+}
+
+// parseUpdate decodes the sections of an UPDATE and verifies its TSIG record
+// the way a server would (RFC 8945 section 4.3.3)
+func parseUpdate(msg []byte, key *tsigKey) (update, []byte) {
+	var u update
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		u.tsigErr = err
+		return u, nil
+	}
+	u.opcode = h.OpCode
+	if u.zone, err = p.Question(); err != nil {
+		u.tsigErr = err
+		return u, nil
+	}
+	p.SkipAllQuestions()
+
+	read := func(next func() (dnsmessage.ResourceHeader, error)) []rr {
+		var rrs []rr
+		for {
+			h, err := next()
+			if err != nil {
+				return rrs
+			}
+			r, err := p.UnknownResource()
+			if err != nil {
+				return rrs
+			}
+			rrs = append(rrs, rr{name: h.Name.String(), typ: h.Type, class: h.Class, ttl: h.TTL, data: r.Data})
+		}
+	}
+	u.prereqs = read(p.AnswerHeader)
+	u.updates = read(p.AuthorityHeader)
+	additional := read(p.AdditionalHeader)
+	if len(additional) == 0 || additional[len(additional)-1].typ != typeTSIG {
+		return u, nil
+	}
+	u.signed = true
+
+	tsig := additional[len(additional)-1]
+	rec, err := key.parseRecord(tsig.data)
+	if err != nil {
+		u.tsigErr = err
+		return u, nil
+	}
+	start := len(msg) - 10 - len(tsig.data) - len(key.name)
+	unsigned := append([]byte(nil), msg[:start]...)
+	binary.BigEndian.PutUint16(unsigned[0:2], rec.originalID)
+	binary.BigEndian.PutUint16(unsigned[10:12], binary.BigEndian.Uint16(unsigned[10:12])-1)
+	mac := hmac.New(key.hash, key.secret)
+	mac.Write(unsigned)
+	mac.Write(key.variables(rec.signed, 0, nil))
+	if !hmac.Equal(mac.Sum(nil), rec.mac) {
+		u.tsigErr = errors.New("BADSIG")
+		return u, nil
+	}
+	return u, rec.mac
+}
+
+// signReply appends a TSIG record covering the request MAC and the reply
+func signReply(key *tsigKey, reply, requestMAC []byte, now time.Time) []byte {
+	signed := uint64(now.Unix())
+	mac := hmac.New(key.hash, key.secret)
+	binary.Write(mac, binary.BigEndian, uint16(len(requestMAC)))
+	mac.Write(requestMAC)
+	mac.Write(reply)
+	mac.Write(key.variables(signed, 0, nil))
+	sum := mac.Sum(nil)
+
+	var rdata bytes.Buffer
+	rdata.Write(key.algorithm)
+	rdata.Write(uint48(signed))
+	binary.Write(&rdata, binary.BigEndian, uint16(tsigFudge))
+	binary.Write(&rdata, binary.BigEndian, uint16(len(sum)))
+	rdata.Write(sum)
+	rdata.Write(reply[0:2])
+	rdata.Write(make([]byte, 4)) // error、other len
+
+	out := bytes.NewBuffer(append([]byte(nil), reply...))
+	out.Write(key.name)
+	binary.Write(out, binary.BigEndian, uint16(typeTSIG))
+	binary.Write(out, binary.BigEndian, uint16(dnsmessage.ClassANY))
+	binary.Write(out, binary.BigEndian, uint32(0))
+	binary.Write(out, binary.BigEndian, uint16(rdata.Len()))
+	out.Write(rdata.Bytes())
+	signedMsg := out.Bytes()
+	binary.BigEndian.PutUint16(signedMsg[10:12], binary.BigEndian.Uint16(signedMsg[10:12])+1)
+	return signedMsg
+}
+
+func newTestProvider(t *testing.T, server, transport string) *Provider {
+	t.Helper()
+	cfg := config.Config{Provider: "rfc2136"}
+	cfg.Options.TTL = 120
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.RFC2136 = &config.RFC2136Config{
+		Server:    server,
+		Transport: transport,
+		KeyName:   tsigTestKeyName,
+		KeySecret: base64.StdEncoding.EncodeToString([]byte(tsigTestSecret)),
+	}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestUpdate(t *testing.T) {
+	s := newFakeServer(t, testTSIGKey(t))
+	for _, transport := range []string{"udp", "tcp"} {
+		p := newTestProvider(t, s.addr, transport)
+		if err := p.UpsertRecord("2001:db8::42"); err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+	}
+
+	got := s.received()
+	if len(got) != 2 {
+		t.Fatalf("want 2 updates, got %d", len(got))
+	}
+	for i, u := range got {
+		if want := []string{"udp", "tcp"}[i]; u.transport != want {
+			t.Errorf("update %d sent over %s, want %s", i, u.transport, want)
+		}
+		if u.opcode != opUpdate {
+			t.Errorf("opcode = %d, want UPDATE", u.opcode)
+		}
+		if u.zone.Name.String() != "example.com." || u.zone.Type != dnsmessage.TypeSOA || u.zone.Class != dnsmessage.ClassINET {
+			t.Errorf("zone section = %v", u.zone)
+		}
+		if len(u.prereqs) != 0 {
+			t.Errorf("unexpected prerequisites %+v", u.prereqs)
+		}
+		if len(u.updates) != 2 {
+			t.Fatalf("want delete and add in the update section, got %+v", u.updates)
+		}
+		del, add := u.updates[0], u.updates[1]
+		if del.name != "home.example.com." || del.typ != dnsmessage.TypeAAAA || del.class != dnsmessage.ClassANY || del.ttl != 0 || len(del.data) != 0 {
+			t.Errorf("first update is not a delete of the AAAA RRset: %+v", del)
+		}
+		if add.name != "home.example.com." || add.typ != dnsmessage.TypeAAAA || add.class != dnsmessage.ClassINET || add.ttl != 120 ||
+			!net.IP(add.data).Equal(net.ParseIP("2001:db8::42")) {
+			t.Errorf("second update is not the new AAAA record: %+v", add)
+		}
+		if !u.signed || u.tsigErr != nil {
+			t.Errorf("request signature not valid: signed=%v err=%v", u.signed, u.tsigErr)
+		}
+	}
+}
+
+func TestUpdatePrerequisite(t *testing.T) {
+	s := newFakeServer(t, testTSIGKey(t))
+	p := newTestProvider(t, s.addr, "")
+	p.cfg.Prerequisite = "name_not_in_use"
+	if err := p.UpsertRecord("2001:db8::42"); err != nil {
+		t.Fatal(err)
+	}
+	u := s.received()[0]
+	if len(u.prereqs) != 1 || u.prereqs[0].name != "home.example.com." || u.prereqs[0].typ != dnsmessage.TypeALL || u.prereqs[0].class != classNONE {
+		t.Errorf("prerequisite section = %+v", u.prereqs)
+	}
+}
+
+func TestUpdateRejected(t *testing.T) {
+	s := newFakeServer(t, testTSIGKey(t))
+	p := newTestProvider(t, s.addr, "")
+
+	tests := []struct {
+		rcode    dnsmessage.RCode
+		unsigned bool
+		badSig   bool
+		want     string
+	}{
+		{dnsmessage.RCodeRefused, false, false, "REFUSED"},
+		{10, false, false, "NOTZONE"},
+		// 不认识密钥的服务器返回未签名的 NOTAUTH，报告 rcode 而不是签名错误
+		{9, true, false, "NOTAUTH"},
+		{dnsmessage.RCodeSuccess, true, false, "not signed"},
+		{dnsmessage.RCodeSuccess, false, true, "does not match"},
+	}
+	for _, tt := range tests {
+		s.set(tt.rcode, tt.unsigned, tt.badSig)
+		err := p.UpsertRecord("2001:db8::42")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("rcode %d unsigned=%v badSig=%v: error %v, want it to contain %q", tt.rcode, tt.unsigned, tt.badSig, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package rfc2136
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"goddns/internal/dnsclient"
+)
+
+const (
+	typeTSIG  dnsmessage.Type = 250
+	tsigFudge                 = 300 // 允许的时钟偏差(秒)
+)
+
+// TSIG error codes (RFC 8945 section 3)
+var tsigErrors = map[uint16]string{
+	16: "BADSIG",
+	17: "BADKEY",
+	18: "BADTIME",
+	22: "BADTRUNC",
+}
+
+var errUnsigned = errors.New("reply is not signed")
+
+// tsigKey signs requests and verifies replies as described in RFC 8945
+type tsigKey struct {
+	name      []byte // 密钥名，规范格式(小写、未压缩)的线路格式
+	algorithm []byte
+	secret    []byte
+	hash      func() hash.Hash
+}
+
+func newTSIGKey(name, algorithm string, secret []byte) (*tsigKey, error) {
+	k := &tsigKey{name: wireName(name), secret: secret}
+	switch strings.ToLower(algorithm) {
+	case "", "hmac-sha256":
+		k.algorithm, k.hash = wireName("hmac-sha256"), sha256.New
+	case "hmac-sha512":
+		k.algorithm, k.hash = wireName("hmac-sha512"), sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported TSIG algorithm '%s'", algorithm)
+	}
+	return k, nil
+}
+
+// wireName encodes name in uncompressed, lower case wire format
+func wireName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(strings.ToLower(dnsclient.Fqdn(name)), "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// variables returns the TSIG variables that are appended to the message
+// before computing the MAC
+func (k *tsigKey) variables(signed uint64, rcode uint16, other []byte) []byte {
+	var b bytes.Buffer
+	b.Write(k.name)
+	binary.Write(&b, binary.BigEndian, uint16(dnsmessage.ClassANY))
+	binary.Write(&b, binary.BigEndian, uint32(0)) // TTL
+	b.Write(k.algorithm)
+	b.Write(uint48(signed))
+	binary.Write(&b, binary.BigEndian, uint16(tsigFudge))
+	binary.Write(&b, binary.BigEndian, rcode)
+	binary.Write(&b, binary.BigEndian, uint16(len(other)))
+	b.Write(other)
+	return b.Bytes()
+}
+
+func uint48(v uint64) []byte {
+	return []byte{byte(v >> 40), byte(v >> 32), byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// sign appends a TSIG record to msg and returns the signed message and its
+// MAC, which is needed to verify the reply
+func (k *tsigKey) sign(msg []byte, now time.Time) ([]byte, []byte) {
+	signed := uint64(now.Unix())
+	mac := hmac.New(k.hash, k.secret)
+	mac.Write(msg)
+	mac.Write(k.variables(signed, 0, nil))
+	sum := mac.Sum(nil)
+
+	var rdata bytes.Buffer
+	rdata.Write(k.algorithm)
+	rdata.Write(uint48(signed))
+	binary.Write(&rdata, binary.BigEndian, uint16(tsigFudge))
+	binary.Write(&rdata, binary.BigEndian, uint16(len(sum)))
+	rdata.Write(sum)
+	rdata.Write(msg[0:2]) // original ID
+	binary.Write(&rdata, binary.BigEndian, uint16(0))
+	binary.Write(&rdata, binary.BigEndian, uint16(0))
+
+	out := bytes.NewBuffer(append([]byte(nil), msg...))
+	out.Write(k.name)
+	binary.Write(out, binary.BigEndian, uint16(typeTSIG))
+	binary.Write(out, binary.BigEndian, uint16(dnsmessage.ClassANY))
+	binary.Write(out, binary.BigEndian, uint32(0))
+	binary.Write(out, binary.BigEndian, uint16(rdata.Len()))
+	out.Write(rdata.Bytes())
+
+	signedMsg := out.Bytes()
+	binary.BigEndian.PutUint16(signedMsg[10:12], binary.BigEndian.Uint16(signedMsg[10:12])+1)
+	return signedMsg, sum
+}
+
+// tsigRecord is the parsed RDATA of a TSIG record
+type tsigRecord struct {
+	signed     uint64
+	fudge      uint16
+	mac        []byte
+	originalID uint16
+	rcode      uint16
+	other      []byte
+}
+
+// verify checks the TSIG record at the end of reply against requestMAC
+func (k *tsigKey) verify(reply, requestMAC []byte, now time.Time) error {
+	var p dnsmessage.Parser
+	if _, err := p.Start(reply); err != nil {
+		return err
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return err
+	}
+	if err := p.SkipAllAnswers(); err != nil {
+		return err
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		return err
+	}
+
+	var hdr dnsmessage.ResourceHeader
+	var rdata []byte
+	for {
+		h, err := p.AdditionalHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if h.Type != typeTSIG {
+			if err := p.SkipAdditional(); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := p.UnknownResource()
+		if err != nil {
+			return err
+		}
+		hdr, rdata = h, r.Data
+	}
+	if rdata == nil {
+		return errUnsigned
+	}
+
+	rec, err := k.parseRecord(rdata)
+	if err != nil {
+		return err
+	}
+	if rec.rcode != 0 {
+		name, ok := tsigErrors[rec.rcode]
+		if !ok {
+			name = fmt.Sprintf("error %d", rec.rcode)
+		}
+		return fmt.Errorf("server rejected the TSIG signature: %s", name)
+	}
+	if !bytes.Equal(wireName(hdr.Name.String()), k.name) {
+		return fmt.Errorf("reply is signed with unknown key %s", hdr.Name)
+	}
+
+	// 去掉末尾的 TSIG 记录并恢复 ARCOUNT 与原始 ID 后重新计算 MAC
+	start := len(reply) - 10 - int(hdr.Length) - len(k.name)
+	if start < 12 || !bytes.EqualFold(reply[start:start+len(k.name)], k.name) {
+		// 服务器压缩了密钥名
+		start = len(reply) - 10 - int(hdr.Length) - 2
+		if start < 12 || reply[start]&0xc0 != 0xc0 {
+			return errors.New("cannot locate the TSIG record in the reply")
+		}
+	}
+	unsigned := append([]byte(nil), reply[:start]...)
+	binary.BigEndian.PutUint16(unsigned[0:2], rec.originalID)
+	binary.BigEndian.PutUint16(unsigned[10:12], binary.BigEndian.Uint16(unsigned[10:12])-1)
+
+	mac := hmac.New(k.hash, k.secret)
+	binary.Write(mac, binary.BigEndian, uint16(len(requestMAC)))
+	mac.Write(requestMAC)
+	mac.Write(unsigned)
+	mac.Write(k.variables(rec.signed, rec.rcode, rec.other))
+	if !hmac.Equal(mac.Sum(nil), rec.mac) {
+		return errors.New("TSIG signature of the reply does not match")
+	}
+
+	skew := int64(now.Unix()) - int64(rec.signed)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > int64(rec.fudge) {
+		return fmt.Errorf("TSIG time of the reply is off by %ds", skew)
+	}
+	return nil
+}
+
+func (k *tsigKey) parseRecord(rdata []byte) (*tsigRecord, error) {
+	bad := errors.New("malformed TSIG record in the reply")
+	if len(rdata) < len(k.algorithm)+10 {
+		return nil, bad
+	}
+	if !bytes.EqualFold(rdata[:len(k.algorithm)], k.algorithm) {
+		return nil, errors.New("reply is signed with a different TSIG algorithm")
+	}
+	b := rdata[len(k.algorithm):]
+	rec := &tsigRecord{}
+	for _, v := range b[:6] {
+		rec.signed = rec.signed<<8 | uint64(v)
+	}
+	rec.fudge = binary.BigEndian.Uint16(b[6:8])
+	macLen := int(binary.BigEndian.Uint16(b[8:10]))
+	b = b[10:]
+	if len(b) < macLen+6 {
+		return nil, bad
+	}
+	rec.mac = b[:macLen]
+	rec.originalID = binary.BigEndian.Uint16(b[macLen:])
+	rec.rcode = binary.BigEndian.Uint16(b[macLen+2:])
+	otherLen := int(binary.BigEndian.Uint16(b[macLen+4:]))
+	if len(b) < macLen+6+otherLen {
+		return nil, bad
+	}
+	rec.other = b[macLen+6 : macLen+6+otherLen]
+	return rec, nil
+}
@@ -0,0 +1,75 @@
+package rfc2136
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// The vectors below were computed outside this package from the field layout
+// of RFC 8945 section 4.3.3, with Python's hmac module: an UPDATE for zone
+// example.com (ID 0x1234) signed with hmac-sha256 key update-key.example.com
+// at 1767225600, and the server's reply signed two seconds later.
+const (
+	tsigTestKeyName = "update-key.example.com"
+	tsigTestSecret  = "goddns-tsig-test-secret-0123456"
+
+	tsigTestRequest = "123428000001000000000000076578616d706c6503636f6d0000060001"
+	tsigTestSigned  = "123428000001000000000001076578616d706c6503636f6d00000600010a7570646174652d6b6579076578616d706c6503636f6d0000fa00ff00000000003d0b686d61632d7368613235360000006955b900012c002005085549ba9c2909ae607f83060c1cce2394c4f21aed236c3ee4cab8baaae436123400000000"
+	tsigTestMAC     = "05085549ba9c2909ae607f83060c1cce2394c4f21aed236c3ee4cab8baaae436"
+	tsigTestReply   = "1234a8000001000000000001076578616d706c6503636f6d00000600010a7570646174652d6b6579076578616d706c6503636f6d0000fa00ff00000000003d0b686d61632d7368613235360000006955b902012c00204c24fe0750b78475ed572e427003e254a0222c9650f3795c0d53b414684ad32e123400000000"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testTSIGKey(t *testing.T) *tsigKey {
+	t.Helper()
+	k, err := newTSIGKey(tsigTestKeyName, "hmac-sha256", []byte(tsigTestSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestTSIGSign(t *testing.T) {
+	k := testTSIGKey(t)
+	signed, mac := k.sign(mustHex(t, tsigTestRequest), time.Unix(1767225600, 0))
+	if want := mustHex(t, tsigTestMAC); !bytes.Equal(mac, want) {
+		t.Fatalf("MAC = %x, want %x", mac, want)
+	}
+	if want := mustHex(t, tsigTestSigned); !bytes.Equal(signed, want) {
+		t.Fatalf("signed message = %x\nwant %x", signed, want)
+	}
+}
+
+func TestTSIGVerify(t *testing.T) {
+	k := testTSIGKey(t)
+	reply, requestMAC := mustHex(t, tsigTestReply), mustHex(t, tsigTestMAC)
+	now := time.Unix(1767225603, 0)
+
+	if err := k.verify(reply, requestMAC, now); err != nil {
+		t.Fatalf("valid reply rejected: %v", err)
+	}
+
+	tampered := append([]byte(nil), reply...)
+	tampered[3] ^= 0x01 // rcode
+	if err := k.verify(tampered, requestMAC, now); err == nil {
+		t.Fatal("tampered reply accepted")
+	}
+	otherMAC := append([]byte(nil), requestMAC...)
+	otherMAC[0] ^= 0xff
+	if err := k.verify(reply, otherMAC, now); err == nil {
+		t.Fatal("reply accepted for a different request MAC")
+	}
+	if err := k.verify(reply, requestMAC, now.Add(10*time.Minute)); err == nil {
+		t.Fatal("reply outside the fudge window accepted")
+	}
+}
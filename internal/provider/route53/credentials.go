@@ -0,0 +1,100 @@
+package route53
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goddns/internal/config"
+)
+
+// credentials is an AWS access key
+type credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// loadCredentials takes the access key from the config, then from the
+// AWS_* environment variables, then from the shared credentials file
+func loadCredentials(cfg config.Route53Config) (credentials, error) {
+	if cfg.AccessKeyID != "" {
+		return credentials{cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken}, nil
+	}
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		secret := os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if secret == "" {
+			return credentials{}, errors.New("AWS_ACCESS_KEY_ID is set but AWS_SECRET_ACCESS_KEY is not")
+		}
+		return credentials{id, secret, os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	path := cfg.CredentialsFile
+	if path == "" {
+		path = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return credentials{}, errors.New("no AWS credentials in config or environment and no home directory")
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := cfg.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	return readCredentialsFile(path, profile)
+}
+
+// readCredentialsFile parses the INI style shared credentials file
+func readCredentialsFile(path, profile string) (credentials, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return credentials{}, fmt.Errorf("no AWS credentials in config or environment: %w", err)
+	}
+	defer f.Close()
+
+	var creds credentials
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return credentials{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return credentials{}, fmt.Errorf("profile '%s' in %s has no access key", profile, path)
+	}
+	return creds, nil
+}
@@ -0,0 +1,309 @@
+package route53
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/dnsclient"
+	"goddns/internal/httpclient"
+	"goddns/internal/log"
+)
+
+const (
+	defaultEndpoint    = "https://route53.amazonaws.com"
+	defaultRegion      = "us-east-1"
+	apiVersion         = "2013-04-01"
+	xmlns              = "https://route53.amazonaws.com/doc/" + apiVersion + "/"
+	defaultWaitTimeout = 180 * time.Second
+	pollInterval       = 5 * time.Second
+	defaultRetries     = 3
+	baseDelay          = 1 * time.Second
+)
+
+// Provider updates records through the Route 53 API
+type Provider struct {
+	cfg      config.Config
+	r53      config.Route53Config
+	fqdn     string
+	client   *http.Client
+	zoneID   string // 缓存查询到的 hosted zone ID
+	endpoint string
+}
+
+// NewProvider constructor
+func NewProvider(cfg config.Config) (*Provider, error) {
+	client, err := httpclient.New(cfg.Proxy, httpclient.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{
+		cfg:      cfg,
+		fqdn:     dnsclient.Fqdn(cfg.RecordName()),
+		client:   client,
+		endpoint: defaultEndpoint,
+	}
+	if cfg.Options.Route53 != nil {
+		p.r53 = *cfg.Options.Route53
+	}
+	if p.r53.Endpoint != "" {
+		p.endpoint = strings.TrimRight(p.r53.Endpoint, "/")
+	}
+	if cfg.Options.ZoneID != "" {
+		p.zoneID = strings.TrimPrefix(cfg.Options.ZoneID, "/hostedzone/")
+	}
+	return p, nil
+}
+
+type apiError struct {
+	Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+type resourceRecordSet struct {
+	Name            string `xml:"Name"`
+	Type            string `xml:"Type"`
+	TTL             int    `xml:"TTL,omitempty"`
+	ResourceRecords []struct {
+		Value string `xml:"Value"`
+	} `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type changeInfo struct {
+	ID     string `xml:"Id"`
+	Status string `xml:"Status"`
+}
+
+// request sends a signed API request and decodes the XML reply into out.
+// Throttling and server errors are retried with exponential backoff.
+func (p *Provider) request(method, path string, query url.Values, body []byte, out interface{}) error {
+	endpoint := p.endpoint + "/" + apiVersion + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	region := p.r53.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	for attempt := 0; ; attempt++ {
+		// 每次请求重新读取凭据，便于外部工具轮换共享凭据文件
+		creds, err := loadCredentials(p.r53)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/xml")
+		}
+		signV4(req, body, creds, region, "route53", time.Now())
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			if attempt == defaultRetries {
+				return fmt.Errorf("API request failed after %d retries: %w", defaultRetries, err)
+			}
+			time.Sleep(baseDelay * time.Duration(1<<attempt))
+			continue
+		}
+		raw, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode < 300 {
+			if out == nil {
+				return nil
+			}
+			if err := xml.Unmarshal(raw, out); err != nil {
+				return fmt.Errorf("failed to decode Route 53 response: %w", err)
+			}
+			return nil
+		}
+
+		var apiErr apiError
+		xml.Unmarshal(raw, &apiErr)
+		retryable := resp.StatusCode >= 500 || apiErr.Error.Code == "Throttling" || apiErr.Error.Code == "PriorRequestNotComplete"
+		if retryable && attempt < defaultRetries {
+			time.Sleep(baseDelay * time.Duration(1<<attempt))
+			continue
+		}
+		if apiErr.Error.Code == "" {
+			return fmt.Errorf("Route 53 API %s %s failed (HTTP %d)", method, path, resp.StatusCode)
+		}
+		return fmt.Errorf("Route 53 API %s %s failed (%s): %s", method, path, apiErr.Error.Code, apiErr.Error.Message)
+	}
+}
+
+// hostedZone returns the configured hosted zone ID, looking it up by name once
+func (p *Provider) hostedZone() (string, error) {
+	if p.zoneID != "" {
+		return p.zoneID, nil
+	}
+	zone := dnsclient.Fqdn(p.cfg.Options.Domain.Zone)
+	var result struct {
+		HostedZones []struct {
+			ID      string `xml:"Id"`
+			Name    string `xml:"Name"`
+			Private bool   `xml:"Config>PrivateZone"`
+		} `xml:"HostedZones>HostedZone"`
+	}
+	query := url.Values{"dnsname": {zone}, "maxitems": {"10"}}
+	if err := p.request("GET", "/hostedzonesbyname", query, nil, &result); err != nil {
+		return "", err
+	}
+	// 结果按名称排序，从 dnsname 开始，需要精确匹配；同名的公有与私有区域优先使用公有区域
+	id := ""
+	for _, hz := range result.HostedZones {
+		if !strings.EqualFold(hz.Name, zone) {
+			continue
+		}
+		if id == "" || !hz.Private {
+			id = strings.TrimPrefix(hz.ID, "/hostedzone/")
+		}
+		if !hz.Private {
+			break
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("failed to find hosted zone %s", zone)
+	}
+	p.zoneID = id
+	return id, nil
+}
+
+// findRecordSet returns the AAAA record set of the record, or nil if it does not exist
+func (p *Provider) findRecordSet(zoneID string) (*resourceRecordSet, error) {
+	var result struct {
+		RecordSets []resourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}
+	query := url.Values{"name": {p.fqdn}, "type": {"AAAA"}, "maxitems": {"1"}}
+	if err := p.request("GET", "/hostedzone/"+zoneID+"/rrset", query, nil, &result); err != nil {
+		return nil, err
+	}
+	for i, rs := range result.RecordSets {
+		if rs.Type == "AAAA" && strings.EqualFold(rs.Name, p.fqdn) {
+			return &result.RecordSets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetRecord implements provider.Provider
+func (p *Provider) GetRecord() ([]string, error) {
+	zoneID, err := p.hostedZone()
+	if err != nil {
+		return nil, err
+	}
+	rs, err := p.findRecordSet(zoneID)
+	if err != nil || rs == nil {
+		return nil, err
+	}
+	var ips []string
+	for _, rr := range rs.ResourceRecords {
+		ips = append(ips, rr.Value)
+	}
+	return ips, nil
+}
+
+// UpsertRecord implements provider.Provider. It returns once Route 53 reports
+// the change as INSYNC on all of its nameservers.
+func (p *Provider) UpsertRecord(ip string) error {
+	zoneID, err := p.hostedZone()
+	if err != nil {
+		return err
+	}
+	existing, err := p.findRecordSet(zoneID)
+	if err != nil {
+		return fmt.Errorf("failed to look up existing record: %w", err)
+	}
+	if existing != nil && existing.TTL == p.cfg.Options.TTL &&
+		len(existing.ResourceRecords) == 1 && existing.ResourceRecords[0].Value == ip {
+		return nil
+	}
+
+	body, err := p.changeBatch(ip)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		ChangeInfo changeInfo `xml:"ChangeInfo"`
+	}
+	if err := p.request("POST", "/hostedzone/"+zoneID+"/rrset/", nil, body, &result); err != nil {
+		return err
+	}
+	return p.waitInSync(result.ChangeInfo)
+}
+
+func (p *Provider) changeBatch(ip string) ([]byte, error) {
+	type resourceRecord struct {
+		Value string `xml:"Value"`
+	}
+	type change struct {
+		Action string `xml:"Action"`
+		Set    struct {
+			Name    string           `xml:"Name"`
+			Type    string           `xml:"Type"`
+			TTL     int              `xml:"TTL"`
+			Records []resourceRecord `xml:"ResourceRecords>ResourceRecord"`
+		} `xml:"ResourceRecordSet"`
+	}
+	var c change
+	c.Action = "UPSERT"
+	c.Set.Name = p.fqdn
+	c.Set.Type = "AAAA"
+	c.Set.TTL = p.cfg.Options.TTL
+	c.Set.Records = []resourceRecord{{Value: ip}}
+
+	req := struct {
+		XMLName xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Comment string   `xml:"ChangeBatch>Comment"`
+		Changes []change `xml:"ChangeBatch>Changes>Change"`
+	}{Xmlns: xmlns, Comment: "goddns", Changes: []change{c}}
+
+	out, err := xml.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// waitInSync polls GetChange until the change is INSYNC or wait_timeout passes
+func (p *Provider) waitInSync(info changeInfo) error {
+	timeout := defaultWaitTimeout
+	if p.r53.WaitTimeout > 0 {
+		timeout = time.Duration(p.r53.WaitTimeout) * time.Second
+	}
+	id := strings.TrimPrefix(info.ID, "/change/")
+	deadline := time.Now().Add(timeout)
+	start := time.Now()
+
+	for info.Status != "INSYNC" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("change %s still %s after %s", id, info.Status, timeout)
+		}
+		time.Sleep(pollInterval)
+		var result struct {
+			ChangeInfo changeInfo `xml:"ChangeInfo"`
+		}
+		if err := p.request("GET", "/change/"+id, nil, nil, &result); err != nil {
+			return fmt.Errorf("failed to get status of change %s: %w", id, err)
+		}
+		info.Status = result.ChangeInfo.Status
+	}
+	log.Info("Route 53 change %s is INSYNC after %s", id, time.Since(start).Round(time.Second))
+	return nil
+}
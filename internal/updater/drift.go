@@ -0,0 +1,138 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/dnsclient"
+	"goddns/internal/log"
+)
+
+const defaultDriftInterval = time.Hour
+
+// driftDue reports whether the live record should be compared again. The
+// marker file's mtime keeps the cadence across one-shot runs.
+func (u *Updater) driftDue(cfg config.Config) bool {
+	if cfg.Drift == nil {
+		return false
+	}
+	interval := defaultDriftInterval
+	if cfg.Drift.Interval > 0 {
+		interval = time.Duration(cfg.Drift.Interval) * time.Second
+	}
+	fi, err := os.Stat(u.driftMarkerFile())
+	return err != nil || time.Since(fi.ModTime()) >= interval
+}
+
+func (u *Updater) driftMarkerFile() string {
+	return config.GetWorkFilePath(u.configFile, u.cfg.WorkDir, "cache.driftcheck")
+}
+
+// checkDrift reads the live record of the targets at the given indexes and
+// returns the indexes whose content differs from ip. Errors are logged and
+// treated as no drift so a lookup problem does not cause an update.
+func (u *Updater) checkDrift(cfg config.Config, name, ip string, targets []target, indexes []int) []int {
+	now := time.Now()
+	if ferr := os.WriteFile(u.driftMarkerFile(), []byte(now.Format(time.RFC3339)), 0644); ferr != nil {
+		log.Warning("Failed to write drift marker: %v", ferr)
+	}
+	u.mu.Lock()
+	u.lastDrift = now
+	u.mu.Unlock()
+
+	// dns 方式按每个目标自己的区域与记录名查询，相同的名称只查询一次
+	type answer struct {
+		live []string
+		err  error
+	}
+	answers := make(map[string]answer)
+	var drifted []int
+	for _, i := range indexes {
+		t := targets[i]
+		var live []string
+		var err error
+		if cfg.Drift.Method == "dns" {
+			key := t.zone + " " + t.fqdn
+			a, ok := answers[key]
+			if !ok {
+				a.live, a.err = liveFromDNS(cfg, t)
+				answers[key] = a
+			}
+			live, err = a.live, a.err
+		} else {
+			live, err = t.provider.GetRecord()
+			sort.Strings(live)
+		}
+		if driftDetected(label(name, t, targets), ip, live, err) {
+			drifted = append(drifted, i)
+		}
+	}
+	return drifted
+}
+
+func driftDetected(name, ip string, live []string, err error) bool {
+	if err != nil {
+		log.Warning("Drift check of %s failed: %v", name, err)
+		return false
+	}
+	if len(live) == 1 && live[0] == ip {
+		log.Info("Drift check: %s matches %s", name, ip)
+		return false
+	}
+
+	shown := "nothing"
+	if len(live) > 0 {
+		shown = strings.Join(live, ", ")
+	}
+	log.Warning("Drift detected: %s has %s, expected %s; correcting", name, shown, ip)
+	return true
+}
+
+// liveFromDNS asks the authoritative servers of the zone of t for its record.
+// drift_check.nameservers serve the top-level zone; the servers of mirrors in
+// other zones are discovered through their NS records.
+func liveFromDNS(cfg config.Config, t target) ([]string, error) {
+	if t.proxied {
+		return nil, errors.New("dns method cannot see the origin address of a proxied record")
+	}
+	var nameservers []string
+	if strings.EqualFold(dnsclient.Fqdn(t.zone), dnsclient.Fqdn(cfg.Options.Domain.Zone)) {
+		nameservers = cfg.Drift.Nameservers
+	}
+	return lookupAuthoritative(nameservers, t.zone, t.fqdn)
+}
+
+// lookupAuthoritative returns the AAAA records of fqdn from the first
+// nameserver of the zone that answers
+func lookupAuthoritative(nameservers []string, zone, fqdn string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var addrs []string
+	if len(nameservers) > 0 {
+		for _, ns := range nameservers {
+			resolved, err := dnsclient.ResolveServer(ctx, ns)
+			if err != nil {
+				return nil, err
+			}
+			addrs = append(addrs, resolved...)
+		}
+	} else {
+		servers, err := dnsclient.AuthoritativeServers(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range servers {
+			addrs = append(addrs, a...)
+		}
+	}
+
+	ips, err := dnsclient.LookupAAAAAny(ctx, addrs, fqdn, false)
+	sort.Strings(ips)
+	return ips, err
+}
@@ -0,0 +1,32 @@
+package updater
+
+import (
+	"testing"
+
+	"goddns/internal/config"
+)
+
+func TestDriftDNSPerTarget(t *testing.T) {
+	ns := newFakeNameserver(t, map[string]string{
+		"home.example.com.": "2001:db8::1",
+		// 镜像发布到同一区域的另一个名称，且被手动改过
+		"alt.example.com.": "2001:db8::99",
+	})
+	cfg, configFile := testConfig(t, "backup", "secondary")
+	cfg.Mirrors[0].Options.Domain.Zone, cfg.Mirrors[0].Options.Domain.Record = "example.com", "alt"
+	cfg.Drift = &config.DriftConfig{Method: "dns", Nameservers: []string{ns.addr}}
+	u, _ := newTestUpdater(t, cfg, configFile)
+	rec := u.records[0]
+
+	drifted := u.checkDrift(cfg, rec.name, "2001:db8::1", rec.targets, []int{0, 1, 2})
+	if len(drifted) != 1 || drifted[0] != 1 {
+		t.Fatalf("want only the backup mirror to drift, got %v", drifted)
+	}
+	// 主目标与 secondary 镜像是同一名称，只查询一次
+	if n := ns.queried("home.example.com."); n != 1 {
+		t.Errorf("home.example.com queried %d times", n)
+	}
+	if n := ns.queried("alt.example.com."); n != 1 {
+		t.Errorf("alt.example.com queried %d times", n)
+	}
+}
@@ -0,0 +1,112 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/log"
+	"goddns/internal/platform/ifaddr"
+)
+
+// neighborHistory remembers when each address of a configured MAC was first
+// seen in the neighbor table, keyed by MAC and then address. Temporary
+// addresses rotate while stable ones stay, so the oldest address wins.
+type neighborHistory map[string]map[string]time.Time
+
+func (u *Updater) neighborHistoryFile() string {
+	return config.GetWorkFilePath(u.configFile, u.cfg.WorkDir, "ndp.state")
+}
+
+// isNeighborHost reports whether rec takes its address from the neighbor table
+func isNeighborHost(rec managedRecord) bool {
+	return rec.host != nil && rec.host.Source == "ndp"
+}
+
+func hasNeighborHosts(records []managedRecord) bool {
+	for _, rec := range records {
+		if isNeighborHost(rec) {
+			return true
+		}
+	}
+	return false
+}
+
+// neighborAddresses reads the neighbor table once and returns the selected
+// address of every ndp host, keyed by record name. Hosts without a usable
+// entry are left out.
+func (u *Updater) neighborAddresses(records []managedRecord) (map[string]string, error) {
+	neighbors, err := ifaddr.GetIPv6Neighbors()
+	if err != nil {
+		return nil, err
+	}
+
+	history := neighborHistory{}
+	if data, err := os.ReadFile(u.neighborHistoryFile()); err == nil {
+		json.Unmarshal(data, &history)
+	}
+
+	now := time.Now()
+	out := map[string]string{}
+	for _, rec := range records {
+		if !isNeighborHost(rec) {
+			continue
+		}
+		mac, err := net.ParseMAC(rec.host.MAC)
+		if err != nil {
+			continue
+		}
+		key := mac.String()
+
+		// 只保留当前仍在邻居表中的地址；主机暂时离线时保留原有记录
+		seen := map[string]time.Time{}
+		for _, n := range neighbors {
+			if n.MAC.String() != key {
+				continue
+			}
+			ip := n.IP.String()
+			if first, ok := history[key][ip]; ok {
+				seen[ip] = first
+			} else {
+				seen[ip] = now
+			}
+		}
+		if len(seen) > 0 {
+			history[key] = seen
+		}
+
+		if ip, err := ifaddr.SelectNeighborAddress(neighbors, mac, history[key]); err == nil {
+			out[rec.name] = ip.String()
+		}
+	}
+
+	if data, err := json.Marshal(history); err == nil {
+		if err := os.WriteFile(u.neighborHistoryFile(), data, 0644); err != nil {
+			log.Warning("Failed to write neighbor history: %v", err)
+		}
+	}
+	return out, nil
+}
+
+// desiredAddress returns the address record rec should point at. An empty
+// result without error means the host is currently unknown and its record is
+// left alone.
+func desiredAddress(rec managedRecord, ip string, neighbors map[string]string, neighborErr error) (string, error) {
+	switch {
+	case rec.host == nil:
+		return ip, nil
+	case rec.host.Source == "ndp":
+		if neighborErr != nil {
+			return "", neighborErr
+		}
+		return neighbors[rec.name], nil
+	}
+	addr, err := ifaddr.HostAddress(net.ParseIP(ip), *rec.host)
+	if err != nil {
+		return "", fmt.Errorf("failed to compose address: %w", err)
+	}
+	return addr.String(), nil
+}
@@ -0,0 +1,819 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/hooks"
+	"goddns/internal/log"
+	"goddns/internal/notify"
+	"goddns/internal/platform/ifaddr"
+	"goddns/internal/provider"
+)
+
+// AddressStatus is the JSON form of an ifaddr.IPv6Info
+type AddressStatus struct {
+	IP           string   `json:"ip"`
+	Scope        string   `json:"scope"`
+	AddressState string   `json:"address_state"`
+	PreferredLft int64    `json:"preferred_lft"` // seconds
+	ValidLft     int64    `json:"valid_lft"`     // seconds
+	Flags        []string `json:"flags,omitempty"`
+	IsCandidate  bool     `json:"is_candidate"`
+}
+
+// Detection is the result of the last address detection
+type Detection struct {
+	Source    string          `json:"source"` // "interface" or "fallback"
+	Addresses []AddressStatus `json:"addresses,omitempty"`
+	Selected  string          `json:"selected,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Time      time.Time       `json:"time"`
+}
+
+// RecordStatus is the state of one managed DNS record
+type RecordStatus struct {
+	Name       string     `json:"name"`
+	Desired    string     `json:"desired"`
+	Published  string     `json:"published"`
+	LastError  string     `json:"last_error,omitempty"`
+	LastUpdate *time.Time `json:"last_update,omitempty"`
+	Paused     bool       `json:"paused,omitempty"`
+	// Propagation is how long the last update took to show up on the
+	// checked nameservers, when verification is enabled
+	Propagation string `json:"propagation,omitempty"`
+	// PropagationError is set when the last update was accepted by the
+	// provider but did not show up on all checked nameservers in time
+	PropagationError string `json:"propagation_error,omitempty"`
+	// Targets holds the result per provider when mirrors are configured
+	Targets []TargetStatus `json:"targets,omitempty"`
+}
+
+// TargetStatus is the state of the record at one provider
+type TargetStatus struct {
+	Name       string     `json:"name"`
+	Provider   string     `json:"provider"`
+	Published  string     `json:"published"`
+	LastError  string     `json:"last_error,omitempty"`
+	LastUpdate *time.Time `json:"last_update,omitempty"`
+}
+
+// target is one provider the record is published to, with its own cache file
+type target struct {
+	name      string
+	kind      string
+	provider  provider.Provider
+	cacheFile string
+	zone      string // zone and name of the record at this provider, for DNS lookups
+	fqdn      string
+	proxied   bool
+}
+
+// managedRecord is one AAAA record kept in sync at every target
+type managedRecord struct {
+	name    string
+	host    *config.HostConfig // nil for provider_options.domain.record
+	targets []target           // the primary provider first, then the mirrors
+}
+
+// Status is a snapshot of the updater state
+type Status struct {
+	Records     []RecordStatus `json:"records"`
+	Detection   *Detection     `json:"detection,omitempty"`
+	LastRun     *time.Time     `json:"last_run,omitempty"`
+	LastSuccess *time.Time     `json:"last_success,omitempty"`
+	LastError   string         `json:"last_error,omitempty"`
+	NextCheck   *time.Time     `json:"next_check,omitempty"`
+	LastDrift   *time.Time     `json:"last_drift_check,omitempty"`
+	Interval    int            `json:"interval"` // seconds
+}
+
+// Updater detects the current address and keeps the DNS records in sync
+type Updater struct {
+	syncMu     sync.Mutex // serializes Sync between the loop and control requests
+	mu         sync.Mutex
+	cfg        config.Config
+	configFile string
+	records    []managedRecord // provider_options.domain.record first, then the hosts
+	notifier   *notify.Dispatcher
+
+	status      []RecordStatus // indexed like records
+	detection   *Detection
+	lastRun     time.Time
+	lastSuccess time.Time
+	lastErr     string
+	nextCheck   time.Time
+	lastDrift   time.Time
+
+	observers     []func(Status, error)
+	heartbeat     time.Duration
+	heartbeatFunc func()
+
+	runCtx        context.Context          // cancels background verifications when Run returns
+	verifications map[string]*verification // running verification per record name
+	verifyWG      sync.WaitGroup
+}
+
+// New creates an Updater for the given config
+func New(cfg config.Config, configFile string) (*Updater, error) {
+	u := &Updater{cfg: cfg, configFile: configFile}
+	records, err := newRecords(cfg, configFile)
+	if err != nil {
+		return nil, err
+	}
+	notifier, err := notify.NewDispatcher(cfg, u.notifyStateFile())
+	if err != nil {
+		return nil, err
+	}
+	u.records = records
+	u.notifier = notifier
+	for _, rec := range records {
+		u.status = append(u.status, newRecordStatus(rec))
+	}
+	return u, nil
+}
+
+// newRecords creates the providers of the main record and of every host
+func newRecords(cfg config.Config, configFile string) ([]managedRecord, error) {
+	var records []managedRecord
+	if cfg.Options.Domain.Record != "" {
+		targets, err := newTargets(cfg, configFile, nil)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, managedRecord{name: recordName(cfg), targets: targets})
+	}
+	for i := range cfg.Hosts {
+		h := &cfg.Hosts[i]
+		targets, err := newTargets(cfg, configFile, h)
+		if err != nil {
+			return nil, fmt.Errorf("host '%s': %w", h.Record, err)
+		}
+		records = append(records, managedRecord{name: config.RecordFQDN(h.Record, cfg.Options.Domain.Zone), host: h, targets: targets})
+	}
+	return records, nil
+}
+
+// newTargets creates the primary provider and one provider per mirror for
+// the main record, or for host when it is not nil. Cache and provider state
+// files of hosts carry the record name and those of mirrors add '@' and the
+// mirror name, which can occur in neither, e.g. cache.<fqdn>@<mirror>.lastip.
+func newTargets(cfg config.Config, configFile string, host *config.HostConfig) ([]target, error) {
+	pcfg, name := cfg, ""
+	if host != nil {
+		pcfg.Options.Domain.Record = host.Record
+		name = recordName(pcfg)
+	}
+	prefix, cache := "", "cache"
+	if name != "" {
+		prefix, cache = name+".", cache+"."+name
+	}
+	p, err := provider.NewTarget(pcfg, configFile, prefix)
+	if err != nil {
+		return nil, err
+	}
+	targets := []target{{
+		name:      config.PrimaryTarget,
+		kind:      cfg.Provider,
+		provider:  p,
+		cacheFile: config.GetWorkFilePath(configFile, cfg.WorkDir, cache+".lastip"),
+		zone:      pcfg.Options.Domain.Zone,
+		fqdn:      recordName(pcfg),
+		proxied:   pcfg.Options.Proxied,
+	}}
+	for _, m := range cfg.Mirrors {
+		mcfg := cfg.MirrorConfig(m)
+		if host != nil {
+			mcfg.Options.Domain.Record = host.Record
+		}
+		p, err := provider.NewTarget(mcfg, configFile, name+"@"+m.Name+".")
+		if err != nil {
+			return nil, fmt.Errorf("mirror '%s': %w", m.Name, err)
+		}
+		targets = append(targets, target{
+			name:      m.Name,
+			kind:      m.Provider,
+			provider:  p,
+			cacheFile: config.GetWorkFilePath(configFile, cfg.WorkDir, cache+"@"+m.Name+".lastip"),
+			zone:      mcfg.Options.Domain.Zone,
+			fqdn:      recordName(mcfg),
+			proxied:   mcfg.Options.Proxied,
+		})
+	}
+	return targets, nil
+}
+
+func newRecordStatus(rec managedRecord) RecordStatus {
+	rs := RecordStatus{
+		Name:      rec.name,
+		Published: config.ReadLastIP(rec.targets[0].cacheFile),
+	}
+	if len(rec.targets) > 1 {
+		for _, t := range rec.targets {
+			rs.Targets = append(rs.Targets, TargetStatus{
+				Name:      t.name,
+				Provider:  t.kind,
+				Published: config.ReadLastIP(t.cacheFile),
+			})
+		}
+	}
+	return rs
+}
+
+// label names the record in log messages, adding the target when mirrors are configured
+func label(name string, t target, targets []target) string {
+	if len(targets) == 1 {
+		return name
+	}
+	return name + " (" + t.name + ")"
+}
+
+func recordName(cfg config.Config) string {
+	return cfg.RecordName()
+}
+
+func (u *Updater) notifyStateFile() string {
+	return config.GetWorkFilePath(u.configFile, u.cfg.WorkDir, "notify.state")
+}
+
+// OnSync registers fn to be called with the new status after every Sync
+func (u *Updater) OnSync(fn func(Status, error)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.observers = append(u.observers, fn)
+}
+
+// SetHeartbeat makes Run call fn every interval while it is waiting for the
+// next check, e.g. for the systemd watchdog
+func (u *Updater) SetHeartbeat(interval time.Duration, fn func()) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.heartbeat = interval
+	u.heartbeatFunc = fn
+}
+
+// Config returns the active configuration
+func (u *Updater) Config() config.Config {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.cfg
+}
+
+// Sync runs one detection and update cycle. force ignores the cached IP.
+func (u *Updater) Sync(force bool) error {
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+	err := u.sync(force)
+
+	st := u.Status()
+	u.mu.Lock()
+	observers := u.observers
+	notifier := u.notifier
+	u.mu.Unlock()
+	notifier.Result(err)
+	for _, fn := range observers {
+		fn(st, err)
+	}
+	return err
+}
+
+func (u *Updater) sync(force bool) error {
+	cfg := u.Config()
+
+	ip, det := detect(cfg, u.previousAddress())
+	u.mu.Lock()
+	u.detection = &det
+	u.lastRun = det.Time
+	u.mu.Unlock()
+
+	u.mu.Lock()
+	records := u.records
+	u.mu.Unlock()
+
+	// 检测不到本机地址时，地址来自邻居表的 ndp 主机仍然照常更新
+	var errs []error
+	var detectErr error
+	if ip == "" {
+		detectErr = fmt.Errorf("failed to detect IPv6 address: %s", det.Error)
+		errs = append(errs, detectErr)
+	} else {
+		log.Info("Current IPv6 address: %s", ip)
+	}
+
+	// 漂移检查周期对所有记录只判断一次
+	driftDue := u.driftDue(cfg)
+	var neighbors map[string]string
+	var neighborErr error
+	if hasNeighborHosts(records) {
+		neighbors, neighborErr = u.neighborAddresses(records)
+	}
+	for i, rec := range records {
+		if detectErr != nil && !isNeighborHost(rec) {
+			u.setRecordResult(i, detectErr)
+			runFailureHooks(cfg, hooks.Env{
+				Record: rec.name,
+				OldIP:  config.ReadLastIP(rec.targets[0].cacheFile),
+				Result: "failure",
+				Error:  detectErr.Error(),
+			})
+			continue
+		}
+		want, err := desiredAddress(rec, ip, neighbors, neighborErr)
+		if err != nil {
+			err = fmt.Errorf("failed to determine address of %s: %w", rec.name, err)
+			u.setRecordResult(i, err)
+			errs = append(errs, err)
+			continue
+		}
+		if want == "" {
+			log.Info("%s is not in the neighbor table, keeping its record", rec.name)
+			continue
+		}
+		if err := u.syncRecord(cfg, i, rec, want, force, driftDue); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+	u.finish(err)
+	return err
+}
+
+func (u *Updater) syncRecord(cfg config.Config, idx int, rec managedRecord, ip string, force, driftDue bool) error {
+	name, targets := rec.name, rec.targets
+	cached := make([]string, len(targets))
+	for i, t := range targets {
+		cached[i] = config.ReadLastIP(t.cacheFile)
+	}
+
+	u.mu.Lock()
+	st := &u.status[idx]
+	st.Desired = ip
+	st.Published = cached[0]
+	paused := st.Paused
+	u.mu.Unlock()
+
+	if paused {
+		log.Info("Record %s is paused, skipping update", name)
+		return nil
+	}
+
+	// 每个目标单独比较缓存，失败的目标下次检测时单独重试
+	var pending, current []int
+	for i := range targets {
+		if force || cached[i] != ip {
+			pending = append(pending, i)
+		} else {
+			current = append(current, i)
+		}
+	}
+	if len(current) > 0 && driftDue {
+		pending = append(pending, u.checkDrift(cfg, name, ip, targets, current)...)
+	}
+	if len(pending) == 0 {
+		log.Info("IP unchanged for %s (%s), skipping update", name, ip)
+		u.setRecordResult(idx, nil)
+		return nil
+	}
+
+	runner := hooks.NewRunner(cfg.Hooks)
+	env := hooks.Env{Record: name, OldIP: cached[0], NewIP: ip, Result: "pending"}
+	err := runner.Run(hooks.PreUpdate, env)
+	var updated []int
+	if err == nil {
+		var errs []string
+		results := upsertTargets(targets, pending, ip)
+		for _, i := range pending {
+			if results[i] != nil {
+				errs = append(errs, label(name, targets[i], targets)+": "+results[i].Error())
+				u.setTargetResult(idx, i, "", results[i])
+			} else {
+				updated = append(updated, i)
+			}
+		}
+		if len(errs) == 1 && len(targets) == 1 {
+			err = results[pending[0]]
+		} else if len(errs) > 0 {
+			err = fmt.Errorf("%d of %d targets failed: %s", len(errs), len(pending), strings.Join(errs, "; "))
+		}
+	}
+
+	// 部分目标失败时，成功的目标仍然记录缓存，避免下次重复更新
+	now := time.Now()
+	primaryUpdated := false
+	for _, i := range updated {
+		t := targets[i]
+		if werr := config.WriteLastIP(t.cacheFile, ip); werr != nil {
+			log.Warning("Failed to write cache file %s: %v", t.cacheFile, werr)
+		}
+		log.Success("DNS record %s updated to %s", label(name, t, targets), ip)
+		u.setTargetResult(idx, i, ip, nil)
+		primaryUpdated = primaryUpdated || i == 0
+	}
+
+	// 主目标的地址变化即发送变更通知，与镜像是否成功无关；失败的镜像重试时主目标缓存已是新地址
+	u.mu.Lock()
+	if primaryUpdated {
+		if cached[0] != ip {
+			u.notifier.Changed(name, cached[0], ip)
+		}
+		st.Published = ip
+		st.LastUpdate = &now
+		st.Propagation, st.PropagationError = "", ""
+	}
+	u.mu.Unlock()
+
+	// 校验只查询主目标所在区域的权威服务器，因此只在主目标更新后进行
+	if primaryUpdated && cfg.Verify != nil {
+		if cfg.Options.Proxied {
+			// 代理模式下解析结果是 Cloudflare 的地址，无法校验
+			log.Info("Skipping DNS verification of %s: record is proxied", name)
+		} else {
+			u.startVerify(cfg, name, ip)
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("failed to update %s: %w", name, err)
+		u.setRecordResult(idx, err)
+		env.Result, env.Error = "failure", err.Error()
+		runFailureHooks(cfg, env)
+		return err
+	}
+	u.setRecordResult(idx, nil)
+
+	env.Result = "success"
+	if err := runner.Run(hooks.PostUpdate, env); err != nil {
+		log.Error("%v", err)
+	}
+	return nil
+}
+
+// upsertTargets updates the targets at the given indexes in parallel and
+// returns the errors indexed like targets
+func upsertTargets(targets []target, indexes []int, ip string) []error {
+	results := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for _, i := range indexes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = targets[i].provider.UpsertRecord(ip)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// setTargetResult records the outcome of record idx at target i; ip is empty on failure
+func (u *Updater) setTargetResult(idx, i int, ip string, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if i >= len(u.status[idx].Targets) {
+		return
+	}
+	ts := &u.status[idx].Targets[i]
+	if err != nil {
+		ts.LastError = err.Error()
+		return
+	}
+	now := time.Now()
+	ts.Published = ip
+	ts.LastError = ""
+	ts.LastUpdate = &now
+}
+
+func runFailureHooks(cfg config.Config, env hooks.Env) {
+	if err := hooks.NewRunner(cfg.Hooks).Run(hooks.OnFailure, env); err != nil {
+		log.Error("%v", err)
+	}
+}
+
+func (u *Updater) setRecordResult(idx int, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err != nil {
+		u.status[idx].LastError = err.Error()
+	} else {
+		u.status[idx].LastError = ""
+	}
+}
+
+func (u *Updater) finish(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err != nil {
+		u.lastErr = err.Error()
+		return
+	}
+	u.lastErr = ""
+	u.lastSuccess = time.Now()
+}
+
+// Reload re-reads the config file and swaps it in. The active config is kept
+// when the new one is invalid.
+func (u *Updater) Reload() error {
+	cfg, _, err := config.LoadConfig(u.configFile)
+	if err != nil {
+		return fmt.Errorf("config reload rejected, keeping current config: %w", err)
+	}
+	records, err := newRecords(cfg, u.configFile)
+	if err != nil {
+		return fmt.Errorf("config reload rejected, keeping current config: %w", err)
+	}
+	notifier, err := notify.NewDispatcher(cfg, config.GetWorkFilePath(u.configFile, cfg.WorkDir, "notify.state"))
+	if err != nil {
+		return fmt.Errorf("config reload rejected, keeping current config: %w", err)
+	}
+
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+	u.mu.Lock()
+	u.cfg = cfg
+	u.notifier = notifier
+	// 名称与目标不变的记录保留状态，其余重新读取缓存；暂停状态按名称保留
+	status := make([]RecordStatus, len(records))
+	for i, rec := range records {
+		status[i] = newRecordStatus(rec)
+		for j, old := range u.records {
+			if old.name != rec.name {
+				continue
+			}
+			if sameTargets(old.targets, rec.targets) {
+				status[i] = u.status[j]
+			}
+			status[i].Paused = u.status[j].Paused
+		}
+	}
+	removed := removedTargets(u.records, records)
+	u.records = records
+	u.status = status
+	u.mu.Unlock()
+	log.Info("Config reloaded from %s", u.configFile)
+
+	for _, r := range removed {
+		deleteTarget(r.name, r.target)
+	}
+	return nil
+}
+
+// removedRecord is a target of a record that is no longer in the config
+type removedRecord struct {
+	name   string
+	target target
+}
+
+// removedTargets returns the targets of old that are missing from records,
+// matched by record name, target name and provider
+func removedTargets(old, records []managedRecord) []removedRecord {
+	var removed []removedRecord
+	for _, o := range old {
+		for _, t := range o.targets {
+			if !hasTarget(records, o.name, t) {
+				removed = append(removed, removedRecord{name: o.name, target: t})
+			}
+		}
+	}
+	return removed
+}
+
+func hasTarget(records []managedRecord, name string, t target) bool {
+	for _, rec := range records {
+		if rec.name != name {
+			continue
+		}
+		for _, n := range rec.targets {
+			if n.name == t.name && n.kind == t.kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deleteTarget removes a dropped record at t when its provider supports
+// deletion, and forgets the cached address so adding it back updates it again
+func deleteTarget(name string, t target) {
+	d, ok := t.provider.(provider.Deleter)
+	if !ok {
+		return
+	}
+	if t.name != config.PrimaryTarget {
+		name += " (" + t.name + ")"
+	}
+	if err := d.DeleteRecord(); err != nil {
+		log.Error("Failed to delete removed record %s: %v", name, err)
+		return
+	}
+	if err := os.Remove(t.cacheFile); err != nil && !os.IsNotExist(err) {
+		log.Warning("Failed to remove cache file %s: %v", t.cacheFile, err)
+	}
+	log.Success("Deleted DNS record %s, which was removed from the config", name)
+}
+
+func sameTargets(a, b []target) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].name != b[i].name || a[i].kind != b[i].kind {
+			return false
+		}
+	}
+	return true
+}
+
+// SetPaused pauses or resumes updates of the named record
+func (u *Updater) SetPaused(name string, paused bool) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := range u.status {
+		if u.status[i].Name == name {
+			u.status[i].Paused = paused
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown record '%s'", name)
+}
+
+// Wait blocks until running verifications finish and pending notifications
+// are delivered
+func (u *Updater) Wait() {
+	u.verifyWG.Wait()
+	u.mu.Lock()
+	notifier := u.notifier
+	u.mu.Unlock()
+	notifier.Wait()
+}
+
+// Run syncs immediately and then every check interval until ctx is cancelled
+func (u *Updater) Run(ctx context.Context) {
+	u.mu.Lock()
+	u.runCtx = ctx
+	u.mu.Unlock()
+
+	for {
+		start := time.Now()
+		if err := u.Sync(false); err != nil {
+			log.Error("%v", err)
+		}
+		u.mu.Lock()
+		every := u.heartbeat
+		u.mu.Unlock()
+		if took := time.Since(start); every > 0 && took > every {
+			log.Warning("Sync took %s, more than half of WatchdogSec; the watchdog may restart goddns", took.Round(time.Second))
+		}
+
+		interval := u.Config().CheckInterval()
+		u.mu.Lock()
+		u.nextCheck = time.Now().Add(interval)
+		u.mu.Unlock()
+
+		if !u.wait(ctx, interval) {
+			return
+		}
+	}
+}
+
+// wait blocks for d, sending heartbeats meanwhile. It returns false when ctx
+// is cancelled. The heartbeat comes only from here, so a sync that hangs
+// stops it and lets the systemd watchdog restart the process.
+func (u *Updater) wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	u.mu.Lock()
+	every, beat := u.heartbeat, u.heartbeatFunc
+	u.mu.Unlock()
+	var tick <-chan time.Time
+	if every > 0 && beat != nil {
+		beat()
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-tick:
+			beat()
+		}
+	}
+}
+
+// Status returns a snapshot of the current state
+func (u *Updater) Status() Status {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	st := Status{
+		Records:     make([]RecordStatus, len(u.status)),
+		LastRun:     optionalTime(u.lastRun),
+		LastSuccess: optionalTime(u.lastSuccess),
+		LastError:   u.lastErr,
+		NextCheck:   optionalTime(u.nextCheck),
+		LastDrift:   optionalTime(u.lastDrift),
+		Interval:    int(u.cfg.CheckInterval() / time.Second),
+	}
+	for i, rs := range u.status {
+		rs.Targets = append([]TargetStatus(nil), rs.Targets...)
+		st.Records[i] = rs
+	}
+	if u.detection != nil {
+		det := *u.detection
+		st.Detection = &det
+	}
+	return st
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// previousAddress is the address get_ip.policy.sticky tries to keep: the one
+// selected by the last detection, or the cached address of the main record
+// after a restart
+func (u *Updater) previousAddress() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.detection != nil && u.detection.Selected != "" {
+		return u.detection.Selected
+	}
+	return config.ReadLastIP(config.GetCacheFilePath(u.configFile, u.cfg.WorkDir))
+}
+
+// detect returns the best IPv6 address, trying the interface first and the
+// fallback URLs second
+func detect(cfg config.Config, current string) (string, Detection) {
+	det := Detection{Time: time.Now()}
+	var errs []error
+
+	if cfg.GetIP.Interface != "" {
+		det.Source = "interface"
+		infos, err := ifaddr.GetAvailableIPv6(cfg.GetIP.Interface)
+		if err == nil {
+			det.Addresses = addressStatuses(cfg, infos)
+			var ip string
+			if ip, err = ifaddr.SelectBestIPv6(cfg, infos, current); err == nil {
+				det.Selected = ip
+				return ip, det
+			}
+		}
+		log.Warning("Interface %s: %v", cfg.GetIP.Interface, err)
+		errs = append(errs, err)
+	}
+
+	if cfg.GetIP.URL != "" || len(cfg.GetIP.URLs) > 0 {
+		det.Source = "fallback"
+		det.Addresses = nil
+		infos, err := ifaddr.GetIPv6Fallback(cfg, false)
+		if err == nil {
+			det.Addresses = addressStatuses(cfg, infos)
+			var ip string
+			if ip, err = ifaddr.SelectBestIPv6(cfg, infos, current); err == nil {
+				det.Selected = ip
+				return ip, det
+			}
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		errs = append(errs, errors.New("no IP source configured in 'get_ip'"))
+	}
+	det.Error = errors.Join(errs...).Error()
+	return "", det
+}
+
+func addressStatuses(cfg config.Config, infos []ifaddr.IPv6Info) []AddressStatus {
+	out := make([]AddressStatus, 0, len(infos))
+	for _, info := range infos {
+		info.IsCandidate = ifaddr.IsCandidate(info, cfg.GetIP.AllowTemporary)
+		out = append(out, NewAddressStatus(info))
+	}
+	return out
+}
+
+// NewAddressStatus converts info to its JSON form
+func NewAddressStatus(info ifaddr.IPv6Info) AddressStatus {
+	return AddressStatus{
+		IP:           info.IP.String(),
+		Scope:        info.Scope,
+		AddressState: info.AddressState,
+		PreferredLft: int64(info.PreferredLft / time.Second),
+		ValidLft:     int64(info.ValidLft / time.Second),
+		Flags:        info.Flags.Names(),
+		IsCandidate:  info.IsCandidate,
+	}
+}
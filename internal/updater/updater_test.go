@@ -0,0 +1,208 @@
+package updater
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"goddns/internal/config"
+)
+
+// fakeProvider records the addresses it was asked to publish
+type fakeProvider struct {
+	mu  sync.Mutex
+	ips []string
+	err error
+}
+
+func (p *fakeProvider) GetRecord() ([]string, error) { return nil, nil }
+
+func (p *fakeProvider) UpsertRecord(ip string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.ips = append(p.ips, ip)
+	return nil
+}
+
+func (p *fakeProvider) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.ips...)
+}
+
+func (p *fakeProvider) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// testConfig publishes home.example.com through exec plugins, which are
+// replaced by fakes before syncing
+func testConfig(t *testing.T, mirrors ...string) (config.Config, string) {
+	t.Helper()
+	cfg := config.Config{Provider: "exec"}
+	cfg.Options.Domain.Zone, cfg.Options.Domain.Record = "example.com", "home"
+	cfg.Options.Exec = &config.ExecConfig{Command: "/bin/true"}
+	for _, name := range mirrors {
+		m := config.MirrorTarget{Name: name, Provider: "exec"}
+		m.Options.Exec = &config.ExecConfig{Command: "/bin/true"}
+		cfg.Mirrors = append(cfg.Mirrors, m)
+	}
+	return cfg, filepath.Join(t.TempDir(), "config.json")
+}
+
+// newTestUpdater returns an updater whose targets publish to fakes
+func newTestUpdater(t *testing.T, cfg config.Config, configFile string) (*Updater, []*fakeProvider) {
+	t.Helper()
+	u, err := New(cfg, configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fakes []*fakeProvider
+	for i := range u.records {
+		for j := range u.records[i].targets {
+			p := &fakeProvider{}
+			u.records[i].targets[j].provider = p
+			fakes = append(fakes, p)
+		}
+	}
+	return u, fakes
+}
+
+func TestMirrorFanOut(t *testing.T) {
+	cfg, configFile := testConfig(t, "backup", "secondary")
+	u, fakes := newTestUpdater(t, cfg, configFile)
+	rec := u.records[0]
+
+	if err := u.syncRecord(cfg, 0, rec, "2001:db8::1", false, false); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range fakes {
+		if got := p.published(); len(got) != 1 || got[0] != "2001:db8::1" {
+			t.Errorf("target %s published %v", rec.targets[i].name, got)
+		}
+		if got := config.ReadLastIP(rec.targets[i].cacheFile); got != "2001:db8::1" {
+			t.Errorf("target %s cached %q", rec.targets[i].name, got)
+		}
+	}
+
+	// 一个镜像失败：其余目标写入缓存，失败的目标下次单独重试
+	fakes[1].fail(errors.New("upstream down"))
+	err := u.syncRecord(cfg, 0, rec, "2001:db8::2", false, false)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 targets failed") || !strings.Contains(err.Error(), "(backup): upstream down") {
+		t.Fatalf("want the backup mirror to fail, got %v", err)
+	}
+	st := u.Status().Records[0]
+	if st.Published != "2001:db8::2" || st.LastError == "" {
+		t.Errorf("record status %+v", st)
+	}
+	if len(st.Targets) != 3 || st.Targets[1].LastError == "" || st.Targets[1].Published != "2001:db8::1" ||
+		st.Targets[0].Published != "2001:db8::2" || st.Targets[2].Published != "2001:db8::2" {
+		t.Errorf("target status %+v", st.Targets)
+	}
+
+	fakes[1].fail(nil)
+	if err := u.syncRecord(cfg, 0, rec, "2001:db8::2", false, false); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{2, 2, 2} {
+		if got := fakes[i].published(); len(got) != want {
+			t.Errorf("target %s published %v, want %d updates", rec.targets[i].name, got, want)
+		}
+	}
+	if st := u.Status().Records[0]; st.LastError != "" || st.Targets[1].LastError != "" || st.Targets[1].Published != "2001:db8::2" {
+		t.Errorf("status after retry %+v", st)
+	}
+
+	// force 更新所有目标
+	if err := u.syncRecord(cfg, 0, rec, "2001:db8::2", true, false); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range fakes {
+		if got := p.published(); len(got) != 3 {
+			t.Errorf("forced sync: target %s published %v", rec.targets[i].name, got)
+		}
+	}
+}
+
+func TestTargetFiles(t *testing.T) {
+	cfg, configFile := testConfig(t, "backup")
+	cfg.Hosts = []config.HostConfig{{Record: "nas", Suffix: "::10"}}
+	records, err := newRecords(cfg, configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cache.lastip", "cache@backup.lastip", "cache.nas.example.com.lastip", "cache.nas.example.com@backup.lastip"}
+	var got []string
+	for _, rec := range records {
+		for _, tg := range rec.targets {
+			got = append(got, filepath.Base(tg.cacheFile))
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cache files %v, want %v", got, want)
+	}
+}
+
+func TestSyncDetectionFailure(t *testing.T) {
+	var mu sync.Mutex
+	status, body := http.StatusOK, "2001:db8::42"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	cfg, configFile := testConfig(t)
+	cfg.GetIP.URL = srv.URL
+	cfg.Hosts = []config.HostConfig{{Record: "nas", Suffix: "::10"}}
+	u, fakes := newTestUpdater(t, cfg, configFile)
+
+	if err := u.Sync(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := fakes[0].published(); len(got) != 1 || got[0] != "2001:db8::42" {
+		t.Errorf("main record published %v", got)
+	}
+	if got := fakes[1].published(); len(got) != 1 || got[0] != "2001:db8::10" {
+		t.Errorf("host record published %v", got)
+	}
+
+	// 检测失败时每条记录都显示错误，已发布的地址不变
+	mu.Lock()
+	status = http.StatusServiceUnavailable
+	mu.Unlock()
+	if err := u.Sync(false); err == nil || !strings.Contains(err.Error(), "failed to detect IPv6 address") {
+		t.Fatalf("want detection error, got %v", err)
+	}
+	st := u.Status()
+	for _, rs := range st.Records {
+		if !strings.Contains(rs.LastError, "failed to detect IPv6 address") || rs.Published == "" {
+			t.Errorf("record %s: status %+v", rs.Name, rs)
+		}
+	}
+	if st.LastError == "" || st.Detection == nil || st.Detection.Error == "" {
+		t.Errorf("updater status %+v", st)
+	}
+
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+	if err := u.Sync(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, rs := range u.Status().Records {
+		if rs.LastError != "" {
+			t.Errorf("record %s keeps error %q after recovery", rs.Name, rs.LastError)
+		}
+	}
+}
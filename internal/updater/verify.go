@@ -0,0 +1,177 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"goddns/internal/config"
+	"goddns/internal/dnsclient"
+	"goddns/internal/log"
+)
+
+const (
+	defaultVerifyTimeout  = 60 * time.Second
+	defaultVerifyInterval = 5 * time.Second
+)
+
+// verifyTarget is one server asked during verification
+type verifyTarget struct {
+	label     string
+	addrs     []string
+	recursive bool
+}
+
+// verification is a background verification of one record
+type verification struct {
+	cancel context.CancelFunc
+}
+
+// startVerify verifies that record name shows ip in the background, outside
+// the sync lock, and reports the result in the record status. A newer update
+// of the same record cancels the previous verification.
+func (u *Updater) startVerify(cfg config.Config, name, ip string) {
+	u.mu.Lock()
+	parent := u.runCtx
+	if parent == nil {
+		parent = context.Background()
+	}
+	if v, ok := u.verifications[name]; ok {
+		v.cancel()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	v := &verification{cancel: cancel}
+	if u.verifications == nil {
+		u.verifications = map[string]*verification{}
+	}
+	u.verifications[name] = v
+	u.mu.Unlock()
+
+	u.verifyWG.Add(1)
+	go func() {
+		defer u.verifyWG.Done()
+		defer cancel()
+		propagation, err := verify(ctx, cfg, cfg.Options.Domain.Zone, name, ip)
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if u.verifications[name] == v {
+			delete(u.verifications, name)
+		}
+		if ctx.Err() == context.Canceled {
+			// 被同一记录的新更新取代，或守护进程正在退出
+			return
+		}
+		if err != nil {
+			log.Warning("DNS record %s was updated but has not propagated: %v", name, err)
+		} else if propagation > 0 {
+			log.Success("DNS record %s propagated to all checked nameservers in %s", name, propagation.Round(time.Millisecond))
+		}
+		for i := range u.status {
+			st := &u.status[i]
+			if st.Name != name || st.Published != ip {
+				continue
+			}
+			if err != nil {
+				st.PropagationError = err.Error()
+			} else if propagation > 0 {
+				st.Propagation = propagation.Round(time.Millisecond).String()
+			}
+		}
+	}()
+}
+
+// verify polls the zone's authoritative nameservers (and configured resolvers)
+// until all of them return ip for fqdn. It returns the propagation time.
+func verify(parent context.Context, cfg config.Config, zone, fqdn, ip string) (time.Duration, error) {
+	vc := cfg.Verify
+	timeout, interval := defaultVerifyTimeout, defaultVerifyInterval
+	if vc.Timeout > 0 {
+		timeout = time.Duration(vc.Timeout) * time.Second
+	}
+	if vc.Interval > 0 {
+		interval = time.Duration(vc.Interval) * time.Second
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	targets, err := verifyTargets(ctx, vc, zone)
+	if err != nil {
+		return 0, err
+	}
+
+	want := net.ParseIP(ip).String()
+	pending := targets
+	for {
+		var still []verifyTarget
+		for _, t := range pending {
+			qctx, qcancel := context.WithTimeout(ctx, dnsclient.DefaultTimeout)
+			ips, err := dnsclient.LookupAAAAAny(qctx, t.addrs, fqdn, t.recursive)
+			qcancel()
+			if err == nil && contains(ips, want) {
+				log.Info("%s returns %s for %s after %s", t.label, want, fqdn, time.Since(start).Round(time.Millisecond))
+				continue
+			}
+			still = append(still, t)
+		}
+		pending = still
+		if len(pending) == 0 {
+			return time.Since(start), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			labels := make([]string, 0, len(pending))
+			for _, t := range pending {
+				labels = append(labels, t.label)
+			}
+			return 0, fmt.Errorf("%s not visible as %s on %s after %s", fqdn, want, strings.Join(labels, ", "), timeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func verifyTargets(ctx context.Context, vc *config.VerifyConfig, zone string) ([]verifyTarget, error) {
+	var targets []verifyTarget
+	if len(vc.Nameservers) > 0 {
+		for _, ns := range vc.Nameservers {
+			addrs, err := dnsclient.ResolveServer(ctx, ns)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve nameserver %s: %w", ns, err)
+			}
+			targets = append(targets, verifyTarget{label: ns, addrs: addrs})
+		}
+	} else {
+		servers, err := dnsclient.AuthoritativeServers(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		for host, addrs := range servers {
+			targets = append(targets, verifyTarget{label: host, addrs: addrs})
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i].label < targets[j].label })
+	}
+
+	for _, r := range vc.Resolvers {
+		addrs, err := dnsclient.ResolveServer(ctx, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve resolver %s: %w", r, err)
+		}
+		targets = append(targets, verifyTarget{label: "resolver " + r, addrs: addrs, recursive: true})
+	}
+	return targets, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package updater
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"goddns/internal/config"
+)
+
+// fakeNameserver answers AAAA queries over UDP from a fixed zone content
+type fakeNameserver struct {
+	addr string
+
+	mu      sync.Mutex
+	records map[string]string // 完整域名(带末尾的点) -> 地址
+	queries map[string]int
+}
+
+func newFakeNameserver(t *testing.T, records map[string]string) *fakeNameserver {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	ns := &fakeNameserver{addr: pc.LocalAddr().String(), records: records, queries: make(map[string]int)}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			ns.mu.Lock()
+			ns.queries[q.Name.String()]++
+			ip, ok := ns.records[q.Name.String()]
+			ns.mu.Unlock()
+			if ok {
+				var aaaa dnsmessage.AAAAResource
+				copy(aaaa.AAAA[:], net.ParseIP(ip))
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &aaaa,
+				})
+			}
+			packed, _ := reply.Pack()
+			pc.WriteTo(packed, from)
+		}
+	}()
+	return ns
+}
+
+func (ns *fakeNameserver) queried(name string) int {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.queries[name]
+}
+
+func (ns *fakeNameserver) set(name, ip string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.records[name] = ip
+}
+
+func TestVerifyInBackground(t *testing.T) {
+	ns := newFakeNameserver(t, map[string]string{"home.example.com.": "2001:db8::1"})
+	cfg, configFile := testConfig(t)
+	cfg.Verify = &config.VerifyConfig{Timeout: 5, Interval: 1, Nameservers: []string{ns.addr}}
+	u, _ := newTestUpdater(t, cfg, configFile)
+	rec := u.records[0]
+
+	// 校验在后台进行，同步立即返回且不持有锁
+	if err := u.syncRecord(cfg, 0, rec, "2001:db8::2", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.syncRecord(cfg, 0, rec, "2001:db8::3", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if st := u.Status().Records[0]; st.Propagation != "" || st.PropagationError != "" {
+		t.Errorf("status before propagation %+v", st)
+	}
+	// 新的更新取消了对 2001:db8::2 的校验
+	ns.set("home.example.com.", "2001:db8::3")
+	u.verifyWG.Wait()
+	if st := u.Status().Records[0]; st.Published != "2001:db8::3" || st.Propagation == "" || st.PropagationError != "" {
+		t.Errorf("status after propagation %+v", st)
+	}
+
+	cfg.Verify.Timeout = 1
+	if err := u.syncRecord(cfg, 0, rec, "2001:db8::4", false, false); err != nil {
+		t.Fatal(err)
+	}
+	u.verifyWG.Wait()
+	st := u.Status().Records[0]
+	if !strings.Contains(st.PropagationError, "home.example.com not visible as 2001:db8::4 on "+ns.addr) || st.Propagation != "" {
+		t.Errorf("status after timeout %+v", st)
+	}
+}